@@ -0,0 +1,56 @@
+package authz
+
+import "bank-app/models"
+
+// Principal is the authenticated caller a policy decides for.
+type Principal struct {
+	UserID uint
+	Role   string
+	// APIKey is set when the caller authenticated with an API key, whose
+	// Scopes then limit what the role allows
+	APIKey bool
+	Scopes []string
+}
+
+func (p Principal) can(permission string) bool {
+	return HasPermission(p.Role, permission) && (!p.APIKey || ScopesAllow(p.Scopes, permission))
+}
+
+// canReadOwn reports whether the caller may read their own resources, which
+// every role may do but an API key only with the read scope.
+func (p Principal) canReadOwn() bool {
+	return !p.APIKey || HasScope(p.Scopes, ScopeRead)
+}
+
+// CanViewUser allows users to see themselves; tellers, auditors and admins
+// can see anyone.
+func CanViewUser(p Principal, userID uint) bool {
+	return (p.UserID == userID && p.canReadOwn()) || p.can(PermReadAll)
+}
+
+// CanViewAccount allows owners to see their own accounts; tellers, auditors
+// and admins can see any account.
+func CanViewAccount(p Principal, account models.Account) bool {
+	return (p.UserID == account.UserID && p.canReadOwn()) || p.can(PermReadAll)
+}
+
+// CanViewTransaction follows the account the transaction was recorded on.
+func CanViewTransaction(p Principal, account models.Account) bool {
+	return CanViewAccount(p, account)
+}
+
+// CanDeposit allows owners to deposit into their own accounts and tellers and
+// admins to deposit into any account on a customer's behalf.
+func CanDeposit(p Principal, account models.Account) bool {
+	return (p.UserID == account.UserID && p.can(PermTransact)) || p.can(PermDepositAny)
+}
+
+// CanWithdraw allows only the owner to take money out of an account.
+func CanWithdraw(p Principal, account models.Account) bool {
+	return p.UserID == account.UserID && p.can(PermTransact)
+}
+
+// CanTransferFrom allows only the owner to send money from an account.
+func CanTransferFrom(p Principal, account models.Account) bool {
+	return p.UserID == account.UserID && p.can(PermTransact)
+}
@@ -0,0 +1,152 @@
+package authz
+
+import (
+	"bank-app/models"
+	"testing"
+)
+
+const (
+	callerID = 1
+	otherID  = 2
+)
+
+func user(role string) Principal {
+	return Principal{UserID: callerID, Role: role}
+}
+
+func apiKey(role string, scopes ...string) Principal {
+	return Principal{UserID: callerID, Role: role, APIKey: true, Scopes: scopes}
+}
+
+type policyCase struct {
+	name   string
+	caller Principal
+	own    bool
+	want   bool
+}
+
+// run checks allowed against every case, on an account or user that belongs
+// to the caller when own is set and to someone else otherwise.
+func run(t *testing.T, cases []policyCase, allowed func(Principal, models.Account) bool) {
+	t.Helper()
+	for _, tc := range cases {
+		owner := uint(otherID)
+		if tc.own {
+			owner = callerID
+		}
+		if got := allowed(tc.caller, models.Account{UserID: owner}); got != tc.want {
+			t.Errorf("%s (own=%v): got %v, want %v", tc.name, tc.own, got, tc.want)
+		}
+	}
+}
+
+func TestCanView(t *testing.T) {
+	cases := []policyCase{
+		{"customer", user(RoleCustomer), true, true},
+		{"customer", user(RoleCustomer), false, false},
+		{"teller", user(RoleTeller), true, true},
+		{"teller", user(RoleTeller), false, true},
+		{"auditor", user(RoleAuditor), true, true},
+		{"auditor", user(RoleAuditor), false, true},
+		{"admin", user(RoleAdmin), true, true},
+		{"admin", user(RoleAdmin), false, true},
+		{"unknown role", user("guest"), true, true},
+		{"unknown role", user("guest"), false, false},
+
+		{"customer key, read", apiKey(RoleCustomer, ScopeRead), true, true},
+		{"customer key, read", apiKey(RoleCustomer, ScopeRead), false, false},
+		{"customer key, transfers", apiKey(RoleCustomer, ScopeTransfers), true, false},
+		{"customer key, no scopes", apiKey(RoleCustomer), true, false},
+		{"teller key, read", apiKey(RoleTeller, ScopeRead), false, true},
+		{"teller key, transfers", apiKey(RoleTeller, ScopeTransfers), false, false},
+		{"auditor key, read", apiKey(RoleAuditor, ScopeRead), false, true},
+		{"admin key, read and transfers", apiKey(RoleAdmin, ScopeRead, ScopeTransfers), false, true},
+		{"admin key, transfers", apiKey(RoleAdmin, ScopeTransfers), false, false},
+	}
+
+	t.Run("account", func(t *testing.T) { run(t, cases, CanViewAccount) })
+	t.Run("transaction", func(t *testing.T) { run(t, cases, CanViewTransaction) })
+	t.Run("user", func(t *testing.T) {
+		run(t, cases, func(p Principal, account models.Account) bool {
+			return CanViewUser(p, account.UserID)
+		})
+	})
+}
+
+func TestCanDeposit(t *testing.T) {
+	run(t, []policyCase{
+		{"customer", user(RoleCustomer), true, true},
+		{"customer", user(RoleCustomer), false, false},
+		{"teller", user(RoleTeller), true, true},
+		{"teller", user(RoleTeller), false, true},
+		{"auditor", user(RoleAuditor), true, false},
+		{"auditor", user(RoleAuditor), false, false},
+		{"admin", user(RoleAdmin), true, true},
+		{"admin", user(RoleAdmin), false, true},
+		{"unknown role", user("guest"), true, false},
+
+		{"customer key, transfers", apiKey(RoleCustomer, ScopeTransfers), true, true},
+		{"customer key, read", apiKey(RoleCustomer, ScopeRead), true, false},
+		{"customer key, no scopes", apiKey(RoleCustomer), true, false},
+		{"teller key, transfers", apiKey(RoleTeller, ScopeTransfers), false, true},
+		{"teller key, read", apiKey(RoleTeller, ScopeRead), false, false},
+		{"auditor key, read and transfers", apiKey(RoleAuditor, ScopeRead, ScopeTransfers), true, false},
+		{"admin key, transfers", apiKey(RoleAdmin, ScopeTransfers), false, true},
+	}, CanDeposit)
+}
+
+// Only the owner can take money out, whatever their role.
+func TestCanWithdrawAndTransferFrom(t *testing.T) {
+	cases := []policyCase{
+		{"customer", user(RoleCustomer), true, true},
+		{"customer", user(RoleCustomer), false, false},
+		{"teller", user(RoleTeller), true, true},
+		{"teller", user(RoleTeller), false, false},
+		{"auditor", user(RoleAuditor), true, false},
+		{"auditor", user(RoleAuditor), false, false},
+		{"admin", user(RoleAdmin), true, true},
+		{"admin", user(RoleAdmin), false, false},
+		{"unknown role", user("guest"), true, false},
+
+		{"customer key, transfers", apiKey(RoleCustomer, ScopeTransfers), true, true},
+		{"customer key, read", apiKey(RoleCustomer, ScopeRead), true, false},
+		{"customer key, no scopes", apiKey(RoleCustomer), true, false},
+		{"teller key, transfers", apiKey(RoleTeller, ScopeTransfers), false, false},
+		{"admin key, read and transfers", apiKey(RoleAdmin, ScopeRead, ScopeTransfers), true, true},
+		{"admin key, read and transfers", apiKey(RoleAdmin, ScopeRead, ScopeTransfers), false, false},
+	}
+
+	t.Run("withdraw", func(t *testing.T) { run(t, cases, CanWithdraw) })
+	t.Run("transfer", func(t *testing.T) { run(t, cases, CanTransferFrom) })
+}
+
+func TestScopesAllow(t *testing.T) {
+	cases := []struct {
+		scopes     []string
+		permission string
+		want       bool
+	}{
+		{[]string{ScopeRead}, PermReadAll, true},
+		{[]string{ScopeRead}, PermReadLedger, true},
+		{[]string{ScopeRead}, PermViewReports, true},
+		{[]string{ScopeRead}, PermTransact, false},
+		{[]string{ScopeTransfers}, PermTransact, true},
+		{[]string{ScopeTransfers}, PermDepositAny, true},
+		{[]string{ScopeTransfers}, PermReadAll, false},
+		{[]string{ScopeRead, ScopeTransfers}, PermTransact, true},
+		{nil, PermReadAll, false},
+		{[]string{"admin"}, PermManageUsers, false},
+	}
+	for _, tc := range cases {
+		if got := ScopesAllow(tc.scopes, tc.permission); got != tc.want {
+			t.Errorf("ScopesAllow(%v, %s) = %v, want %v", tc.scopes, tc.permission, got, tc.want)
+		}
+	}
+
+	// No scope grants administration, so an admin's key can never manage
+	for _, permission := range []string{PermManageUsers, PermManageAccounts, PermManageProducts} {
+		if ScopesAllow([]string{ScopeRead, ScopeTransfers}, permission) {
+			t.Errorf("%s is granted to an API key", permission)
+		}
+	}
+}
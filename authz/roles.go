@@ -0,0 +1,65 @@
+package authz
+
+// Roles a user can hold. Every new user starts as a customer.
+const (
+	RoleCustomer = "customer"
+	RoleTeller   = "teller"
+	RoleAdmin    = "admin"
+	RoleAuditor  = "auditor"
+)
+
+// Permissions guard routes independently of who owns the resource.
+const (
+	// Open accounts and move money
+	PermTransact = "accounts:transact"
+	// Deposit into any customer's account
+	PermDepositAny = "accounts:deposit_any"
+	// Read every customer's accounts and transactions
+	PermReadAll = "accounts:read_all"
+	// Read the general ledger
+	PermReadLedger = "ledger:read"
+	// View bank-wide reports such as the transaction summary
+	PermViewReports = "reports:view"
+	// Change other users' roles
+	PermManageUsers = "users:manage"
+	// Freeze, unfreeze or otherwise change the status of any account, and waive fees
+	PermManageAccounts = "accounts:manage"
+	// Define the account products customers can open
+	PermManageProducts = "products:manage"
+)
+
+var rolePermissions = map[string]map[string]bool{
+	RoleCustomer: {
+		PermTransact: true,
+	},
+	RoleTeller: {
+		PermTransact:   true,
+		PermDepositAny: true,
+		PermReadAll:    true,
+	},
+	RoleAuditor: {
+		PermReadAll:    true,
+		PermReadLedger: true,
+	},
+	RoleAdmin: {
+		PermTransact:       true,
+		PermDepositAny:     true,
+		PermReadAll:        true,
+		PermReadLedger:     true,
+		PermViewReports:    true,
+		PermManageUsers:    true,
+		PermManageAccounts: true,
+		PermManageProducts: true,
+	},
+}
+
+// ValidRole reports whether role is one of the known roles.
+func ValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// HasPermission reports whether role grants permission.
+func HasPermission(role, permission string) bool {
+	return rolePermissions[role][permission]
+}
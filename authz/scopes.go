@@ -0,0 +1,49 @@
+package authz
+
+// Scopes limit what an API key can do. A key never has more permissions than
+// the role of the user who owns it.
+const (
+	// Read accounts, transactions and, if the role allows, the ledger and
+	// reports
+	ScopeRead = "read"
+	// Open accounts and move money
+	ScopeTransfers = "transfers"
+)
+
+var scopePermissions = map[string]map[string]bool{
+	ScopeRead: {
+		PermReadAll:     true,
+		PermReadLedger:  true,
+		PermViewReports: true,
+	},
+	ScopeTransfers: {
+		PermTransact:   true,
+		PermDepositAny: true,
+	},
+}
+
+// ValidScope reports whether scope is one of the known scopes.
+func ValidScope(scope string) bool {
+	_, ok := scopePermissions[scope]
+	return ok
+}
+
+// ScopesAllow reports whether any of scopes covers permission.
+func ScopesAllow(scopes []string, permission string) bool {
+	for _, scope := range scopes {
+		if scopePermissions[scope][permission] {
+			return true
+		}
+	}
+	return false
+}
+
+// HasScope reports whether scopes contains scope.
+func HasScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
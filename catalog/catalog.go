@@ -0,0 +1,150 @@
+package catalog
+
+import (
+	"bank-app/models"
+	"bank-app/money"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Day-count conventions for interest.
+const (
+	// Actual days elapsed over a 365-day year
+	DayCountActual365 = "ACT/365"
+	// Every month counts as 30 days of a 360-day year
+	DayCount30360 = "30/360"
+)
+
+// Fee kinds a product can charge.
+const (
+	FeeMonthlyMaintenance = "monthly_maintenance"
+	// Charged for a month in which the balance fell below MinimumBalance
+	FeeMinimumBalance = "minimum_balance"
+	FeeTransfer       = "transfer"
+	// Charged per withdrawal beyond FreeCount in a month
+	FeeExcessWithdrawal = "excess_withdrawal"
+)
+
+// Rates are capped at 100% a year.
+const maxRateBps = 10000
+
+var (
+	codePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{1,31}$`)
+	dayCounts   = map[string]bool{DayCountActual365: true, DayCount30360: true}
+	feeKinds    = map[string]bool{
+		FeeMonthlyMaintenance: true,
+		FeeMinimumBalance:     true,
+		FeeTransfer:           true,
+		FeeExcessWithdrawal:   true,
+	}
+)
+
+// ValidFeeKind reports whether kind is a fee kind products can charge.
+func ValidFeeKind(kind string) bool {
+	return feeKinds[kind]
+}
+
+// ValidationError lists every problem found in a product.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid product: " + strings.Join(e.Problems, "; ")
+}
+
+// Validate checks a product and its rules before it is saved.
+func Validate(p models.AccountProduct) error {
+	var problems []string
+
+	if !codePattern.MatchString(p.Code) {
+		problems = append(problems, "code must be 2-32 lower case letters, digits or underscores, starting with a letter")
+	}
+	if strings.TrimSpace(p.Name) == "" {
+		problems = append(problems, "name is required")
+	}
+	if p.Currency != "" && !p.Currency.Valid() {
+		problems = append(problems, fmt.Sprintf("unsupported currency %q", p.Currency))
+	}
+	if p.Currency == "" && hasMoneyAmounts(p) {
+		problems = append(problems, "currency is required when min_opening_balance, interest tier min_balance or fee_rules are set")
+	}
+	if p.MinOpeningBalance.IsNegative() {
+		problems = append(problems, "min_opening_balance cannot be negative")
+	}
+	if p.MaxAccountsPerUser < 0 {
+		problems = append(problems, "max_accounts_per_user cannot be negative")
+	}
+	if !dayCounts[p.InterestDayCount] {
+		problems = append(problems, "interest_day_count must be "+DayCountActual365+" or "+DayCount30360)
+	}
+
+	tiers := map[int64]bool{}
+	for _, tier := range p.InterestTiers {
+		if tier.MinBalance.IsNegative() {
+			problems = append(problems, "interest tier min_balance cannot be negative")
+		}
+		if tier.RateBps < 0 || tier.RateBps > maxRateBps {
+			problems = append(problems, fmt.Sprintf("interest tier rate_bps must be between 0 and %d", maxRateBps))
+		}
+		if tiers[tier.MinBalance.Minor()] {
+			problems = append(problems, "interest tiers must have distinct min_balance values")
+		}
+		tiers[tier.MinBalance.Minor()] = true
+	}
+
+	kinds := map[string]bool{}
+	for _, rule := range p.FeeRules {
+		if !feeKinds[rule.Kind] {
+			problems = append(problems, fmt.Sprintf("unknown fee kind %q", rule.Kind))
+		}
+		if kinds[rule.Kind] {
+			problems = append(problems, fmt.Sprintf("fee kind %q appears more than once", rule.Kind))
+		}
+		kinds[rule.Kind] = true
+		if !rule.Amount.IsPositive() {
+			problems = append(problems, "fee amount must be positive")
+		}
+		if rule.Kind == FeeMinimumBalance && !rule.MinimumBalance.IsPositive() {
+			problems = append(problems, "minimum_balance fee needs a positive minimum_balance")
+		}
+		if rule.FreeCount < 0 {
+			problems = append(problems, "fee free_count cannot be negative")
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// hasMoneyAmounts reports whether any of the product's rules is an amount of
+// money, which only means something in a known currency.
+func hasMoneyAmounts(p models.AccountProduct) bool {
+	if !p.MinOpeningBalance.IsZero() || len(p.FeeRules) > 0 {
+		return true
+	}
+	for _, tier := range p.InterestTiers {
+		if !tier.MinBalance.IsZero() {
+			return true
+		}
+	}
+	return false
+}
+
+// AppliesTo reports whether the product's money amounts are in currency.
+// Products without a currency have no money amounts, so they apply to any.
+func AppliesTo(p models.AccountProduct, currency money.Currency) bool {
+	return p.Currency == "" || p.Currency == currency
+}
+
+// Defaults are the products every deployment starts with, matching the
+// account types that existed before the catalog.
+func Defaults() []models.AccountProduct {
+	return []models.AccountProduct{
+		{Code: "savings", Name: "Savings", MaxAccountsPerUser: 1, InterestDayCount: DayCountActual365},
+		{Code: "checking", Name: "Checking", MaxAccountsPerUser: 1, InterestDayCount: DayCountActual365},
+	}
+}
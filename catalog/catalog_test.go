@@ -0,0 +1,136 @@
+package catalog
+
+import (
+	"bank-app/models"
+	"bank-app/money"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func validProduct() models.AccountProduct {
+	return models.AccountProduct{
+		Code:               "premium_savings",
+		Name:               "Premium Savings",
+		Currency:           money.USD,
+		MinOpeningBalance:  money.FromMinor(100_00),
+		MaxAccountsPerUser: 2,
+		InterestDayCount:   DayCount30360,
+		InterestTiers: []models.InterestTier{
+			{MinBalance: 0, RateBps: 100},
+			{MinBalance: money.FromMinor(10_000_00), RateBps: 250},
+		},
+		FeeRules: []models.FeeRule{
+			{Kind: FeeMonthlyMaintenance, Amount: money.FromMinor(5_00)},
+			{Kind: FeeMinimumBalance, Amount: money.FromMinor(10_00), MinimumBalance: money.FromMinor(500_00)},
+			{Kind: FeeExcessWithdrawal, Amount: money.FromMinor(2_00), FreeCount: 6},
+			{Kind: FeeTransfer, Amount: money.FromMinor(1_00)},
+		},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cases := []struct {
+		name   string
+		change func(p *models.AccountProduct)
+		// Substrings of the expected problems; none means valid
+		problems []string
+	}{
+		{"valid", func(p *models.AccountProduct) {}, nil},
+		{"defaults", nil, nil},
+		{"no currency without amounts", func(p *models.AccountProduct) {
+			p.Currency = ""
+			p.MinOpeningBalance = 0
+			p.InterestTiers = []models.InterestTier{{MinBalance: 0, RateBps: 100}}
+			p.FeeRules = nil
+		}, nil},
+		{"rate at the cap", func(p *models.AccountProduct) { p.InterestTiers[0].RateBps = 10000 }, nil},
+		{"no limit", func(p *models.AccountProduct) { p.MaxAccountsPerUser = 0 }, nil},
+
+		{"short code", func(p *models.AccountProduct) { p.Code = "a" }, []string{"code must be"}},
+		{"upper case code", func(p *models.AccountProduct) { p.Code = "Savings" }, []string{"code must be"}},
+		{"code starting with a digit", func(p *models.AccountProduct) { p.Code = "1savings" }, []string{"code must be"}},
+		{"long code", func(p *models.AccountProduct) { p.Code = "s" + strings.Repeat("a", 32) }, []string{"code must be"}},
+		{"blank name", func(p *models.AccountProduct) { p.Name = "  " }, []string{"name is required"}},
+		{"unknown currency", func(p *models.AccountProduct) { p.Currency = "XYZ" }, []string{"unsupported currency"}},
+		{"opening balance without currency", func(p *models.AccountProduct) {
+			p.Currency = ""
+			p.InterestTiers = nil
+			p.FeeRules = nil
+		}, []string{"currency is required"}},
+		{"tier without currency", func(p *models.AccountProduct) {
+			p.Currency = ""
+			p.MinOpeningBalance = 0
+			p.FeeRules = nil
+		}, []string{"currency is required"}},
+		{"fee without currency", func(p *models.AccountProduct) {
+			p.Currency = ""
+			p.MinOpeningBalance = 0
+			p.InterestTiers = nil
+		}, []string{"currency is required"}},
+		{"negative opening balance", func(p *models.AccountProduct) { p.MinOpeningBalance = money.FromMinor(-1) }, []string{"min_opening_balance cannot be negative"}},
+		{"negative limit", func(p *models.AccountProduct) { p.MaxAccountsPerUser = -1 }, []string{"max_accounts_per_user"}},
+		{"unknown day count", func(p *models.AccountProduct) { p.InterestDayCount = "ACT/360" }, []string{"interest_day_count"}},
+		{"missing day count", func(p *models.AccountProduct) { p.InterestDayCount = "" }, []string{"interest_day_count"}},
+		{"negative tier", func(p *models.AccountProduct) { p.InterestTiers[1].MinBalance = money.FromMinor(-1) }, []string{"min_balance cannot be negative"}},
+		{"negative rate", func(p *models.AccountProduct) { p.InterestTiers[0].RateBps = -1 }, []string{"rate_bps"}},
+		{"rate over the cap", func(p *models.AccountProduct) { p.InterestTiers[0].RateBps = 10001 }, []string{"rate_bps"}},
+		{"duplicate tiers", func(p *models.AccountProduct) { p.InterestTiers[1].MinBalance = 0 }, []string{"distinct min_balance"}},
+		{"unknown fee kind", func(p *models.AccountProduct) { p.FeeRules[0].Kind = "overdraft" }, []string{"unknown fee kind"}},
+		{"duplicate fee kind", func(p *models.AccountProduct) { p.FeeRules[1].Kind = FeeMonthlyMaintenance }, []string{"more than once"}},
+		{"zero fee", func(p *models.AccountProduct) { p.FeeRules[3].Amount = 0 }, []string{"fee amount must be positive"}},
+		{"minimum balance fee without a minimum", func(p *models.AccountProduct) { p.FeeRules[1].MinimumBalance = 0 }, []string{"needs a positive minimum_balance"}},
+		{"negative free count", func(p *models.AccountProduct) { p.FeeRules[2].FreeCount = -1 }, []string{"free_count cannot be negative"}},
+		{"several problems", func(p *models.AccountProduct) {
+			p.Name = ""
+			p.MaxAccountsPerUser = -1
+			p.FeeRules[0].Amount = -5
+		}, []string{"name is required", "max_accounts_per_user", "fee amount must be positive"}},
+	}
+	for _, tc := range cases {
+		var products []models.AccountProduct
+		if tc.change == nil {
+			products = Defaults()
+		} else {
+			p := validProduct()
+			tc.change(&p)
+			products = []models.AccountProduct{p}
+		}
+
+		for _, p := range products {
+			err := Validate(p)
+			if tc.problems == nil {
+				if err != nil {
+					t.Errorf("%s: %v", tc.name, err)
+				}
+				continue
+			}
+
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Errorf("%s: got %v, want a ValidationError", tc.name, err)
+				continue
+			}
+			if len(validationErr.Problems) != len(tc.problems) {
+				t.Errorf("%s: problems %q, want %d", tc.name, validationErr.Problems, len(tc.problems))
+			}
+			for _, want := range tc.problems {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("%s: %q does not mention %q", tc.name, err, want)
+				}
+			}
+		}
+	}
+}
+
+func TestAppliesTo(t *testing.T) {
+	if !AppliesTo(models.AccountProduct{}, money.EUR) {
+		t.Error("product without a currency does not apply to EUR")
+	}
+	if !AppliesTo(models.AccountProduct{Currency: money.USD}, money.USD) {
+		t.Error("USD product does not apply to USD")
+	}
+	if AppliesTo(models.AccountProduct{Currency: money.USD}, money.EUR) {
+		t.Error("USD product applies to EUR")
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bank-app/notifier"
+	"bank-app/rabbitmq"
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+)
+
+// The notifier consumes banking events and emails the customers they concern.
+func main() {
+	topology, err := rabbitmq.LoadTopology()
+	if err != nil {
+		log.Fatalf("Failed to load RabbitMQ topology: %v", err)
+	}
+
+	queueName := os.Getenv("NOTIFIER_QUEUE")
+	if queueName == "" {
+		queueName = "bank.notifications"
+	}
+	queue, ok := topology.Queue(queueName)
+	if !ok {
+		log.Fatalf("Queue %s is not part of RABBITMQ_QUEUES", queueName)
+	}
+
+	maxRetries := 5
+	if value := os.Getenv("NOTIFIER_MAX_RETRIES"); value != "" {
+		if maxRetries, err = strconv.Atoi(value); err != nil || maxRetries < 0 {
+			log.Fatalf("Invalid NOTIFIER_MAX_RETRIES %q", value)
+		}
+	}
+
+	sender, err := notifier.LoadSender()
+	if err != nil {
+		log.Fatalf("Failed to initialize mail sender: %v", err)
+	}
+
+	conn := rabbitmq.Dial(os.Getenv("RABBITMQ_URL"), topology.Declare)
+	defer conn.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	consumer := &notifier.Consumer{
+		Conn:       conn,
+		Topology:   topology,
+		Queue:      queue,
+		Sender:     sender,
+		MaxRetries: maxRetries,
+		Prefetch:   10,
+	}
+	consumer.Run(ctx)
+	log.Println("Notifier stopped")
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"bank-app/catalog"
 	"bank-app/models"
 	"fmt"
 	"log"
@@ -40,16 +41,119 @@ func ConnectDB() {
 	}
 	log.Println("Database connected successfully!")
 
+	if err := Migrate(); err != nil {
+		log.Fatal("Failed to migrate database: ", err)
+	}
+	log.Println("Database schema migrated successfully!")
+
+	seedAccountProducts()
+	bootstrapAdmin()
+}
+
+// Migrate brings the schema of DB up to date.
+func Migrate() error {
+	// Users who signed up before email verification existed are treated as
+	// verified, so they can keep moving money
+	grandfatherVerified := DB.HasTable(&models.User{}) && !DB.Dialect().HasColumn("users", "email_verified_at")
+
 	// Automatically migrate the schema
-	err = DB.AutoMigrate(
+	err := DB.AutoMigrate(
 		&models.User{},    // Migrating the User model
 		&models.Account{}, // Migrating the Account model
 		&models.Transaction{},
+		&models.LedgerAccount{},
+		&models.JournalEntry{},
+		&models.Posting{},
+		&models.IdempotencyKey{},
+		&models.OutboxEvent{},
+		&models.Session{},
+		&models.RefreshToken{},
+		&models.RecoveryCode{},
+		&models.LoginThrottle{},
+		&models.UserToken{},
+		&models.PasswordHistory{},
+		&models.APIKey{},
+		&models.FXConversion{},
+		&models.AccountStatusChange{},
+		&models.AccountProduct{},
+		&models.InterestTier{},
+		&models.FeeRule{},
+		&models.InterestAccrual{},
+		&models.InterestCapitalization{},
+		&models.FeeWaiver{},
 	).Error
 	if err != nil {
-		log.Fatal("Failed to migrate database: ", err)
+		return err
 	}
-	log.Println("Database schema migrated successfully!")
+	if grandfatherVerified {
+		if err := DB.Exec("UPDATE users SET email_verified_at = created_at WHERE email_verified_at IS NULL").Error; err != nil {
+			return fmt.Errorf("marking existing users verified: %w", err)
+		}
+	}
+	if err := migrateMoneyColumns(); err != nil {
+		return fmt.Errorf("migrating money columns: %w", err)
+	}
+	return nil
+}
+
+// seedAccountProducts creates any default product that has never existed.
+// Products an admin has since retired are not brought back.
+func seedAccountProducts() {
+	for _, product := range catalog.Defaults() {
+		if err := DB.Unscoped().Where("code = ?", product.Code).FirstOrCreate(&product).Error; err != nil {
+			log.Fatal("Failed to seed account products: ", err)
+		}
+	}
+}
+
+// bootstrapAdmin promotes the user whose email is in BOOTSTRAP_ADMIN_EMAIL to
+// admin, so a fresh deployment has someone who can assign roles.
+func bootstrapAdmin() {
+	email := os.Getenv("BOOTSTRAP_ADMIN_EMAIL")
+	if email == "" {
+		return
+	}
+
+	result := DB.Model(&models.User{}).Where("email = ?", email).Update("role", "admin")
+	if result.Error != nil {
+		log.Fatal("Failed to bootstrap admin user: ", result.Error)
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("Granted admin role to %s\n", email)
+	}
+}
+
+// migrateMoneyColumns copies the legacy float `balance` and `amount` columns
+// into their integer minor-unit replacements, then drops the legacy columns.
+// Dropping them makes this a one-shot migration: a later restart must not
+// copy a stale float back over a balance that has since reached zero. Both
+// steps run before the server accepts requests, so a crash in between only
+// repeats the copy.
+func migrateMoneyColumns() error {
+	legacy := []struct {
+		table, from, to string
+	}{
+		{"accounts", "balance", "balance_minor"},
+		{"transactions", "amount", "amount_minor"},
+	}
+
+	for _, col := range legacy {
+		if !DB.Dialect().HasColumn(col.table, col.from) {
+			continue
+		}
+		query := fmt.Sprintf(
+			"UPDATE %s SET %s = CAST(ROUND(%s * 100) AS SIGNED) WHERE %s = 0 AND %s <> 0",
+			col.table, col.to, col.from, col.to, col.from,
+		)
+		if err := DB.Exec(query).Error; err != nil {
+			return err
+		}
+		if err := DB.Exec(fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", col.table, col.from)).Error; err != nil {
+			return err
+		}
+		log.Printf("Migrated %s.%s to %s and dropped the legacy column\n", col.table, col.from, col.to)
+	}
+	return nil
 }
 
 func CloseDB() {
@@ -0,0 +1,52 @@
+package config
+
+import (
+	"bank-app/events"
+	"bank-app/rabbitmq"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+)
+
+// NewEventPublisher builds the publisher selected by EVENT_PUBLISHER:
+//
+//	amqp   - RabbitMQ at RABBITMQ_URL (the default when RABBITMQ_URL is set)
+//	file   - one JSON line per event appended to EVENT_LOG_PATH
+//	memory - discarded, for local development without a broker
+//
+// Events are never delivered in memory mode, so it must be chosen
+// explicitly; with neither EVENT_PUBLISHER nor RABBITMQ_URL set, start-up
+// fails rather than losing notifications unnoticed.
+func NewEventPublisher() (events.EventPublisher, error) {
+	kind := os.Getenv("EVENT_PUBLISHER")
+	if kind == "" {
+		if os.Getenv("RABBITMQ_URL") == "" {
+			return nil, errors.New("no event publisher configured: set RABBITMQ_URL, or EVENT_PUBLISHER=memory for local development")
+		}
+		kind = "amqp"
+	}
+
+	switch kind {
+	case "amqp":
+		if os.Getenv("RABBITMQ_URL") == "" {
+			return nil, errors.New("EVENT_PUBLISHER=amqp needs RABBITMQ_URL")
+		}
+		topology, err := rabbitmq.LoadTopology()
+		if err != nil {
+			return nil, err
+		}
+		return rabbitmq.NewPublisher(os.Getenv("RABBITMQ_URL"), topology), nil
+	case "file":
+		path := os.Getenv("EVENT_LOG_PATH")
+		if path == "" {
+			path = "events.jsonl"
+		}
+		return events.NewFilePublisher(path)
+	case "memory":
+		log.Println("EVENT_PUBLISHER=memory, events will not be delivered")
+		return events.DiscardPublisher{}, nil
+	default:
+		return nil, fmt.Errorf("unknown EVENT_PUBLISHER %q", kind)
+	}
+}
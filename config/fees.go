@@ -0,0 +1,10 @@
+package config
+
+import "time"
+
+// FeeRunInterval is how often the fee engine checks for months whose
+// maintenance and minimum balance fees are due. It is read from
+// FEE_RUN_INTERVAL (e.g. "1h", "15m").
+func FeeRunInterval() time.Duration {
+	return durationFromEnv("FEE_RUN_INTERVAL", time.Hour)
+}
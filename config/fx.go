@@ -0,0 +1,36 @@
+package config
+
+import (
+	"bank-app/fx"
+	"log"
+	"os"
+	"time"
+)
+
+// FXRates quotes the exchange rates used for cross-currency transfers.
+var FXRates fx.FXRateProvider
+
+// LoadFXRates sets FXRates from the CSV file in FX_RATES_PATH. Without one
+// no rates are known and cross-currency transfers are refused.
+func LoadFXRates() error {
+	path := os.Getenv("FX_RATES_PATH")
+	if path == "" {
+		log.Println("FX_RATES_PATH is not set, cross-currency transfers are disabled")
+		FXRates = fx.NewStaticProvider(time.Now())
+		return nil
+	}
+
+	provider, err := fx.LoadCSV(path)
+	if err != nil {
+		return err
+	}
+	FXRates = provider
+	return nil
+}
+
+// FXSpreadBps is the margin, in basis points of the converted amount, that
+// the bank keeps on a conversion. It is read from FX_SPREAD_BPS; 0 disables
+// the spread.
+func FXSpreadBps() int {
+	return nonNegativeIntFromEnv("FX_SPREAD_BPS", 50)
+}
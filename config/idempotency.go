@@ -0,0 +1,17 @@
+package config
+
+import "time"
+
+// IdempotencyKeyTTL is how long a stored Idempotency-Key response is
+// replayed. It is read from IDEMPOTENCY_KEY_TTL (e.g. "24h", "30m").
+func IdempotencyKeyTTL() time.Duration {
+	return durationFromEnv("IDEMPOTENCY_KEY_TTL", 24*time.Hour)
+}
+
+// IdempotencyInFlightTimeout is how long a request may hold its
+// Idempotency-Key before the key is treated as abandoned and a retry may run
+// it again. It is read from IDEMPOTENCY_IN_FLIGHT_TIMEOUT and must exceed the
+// longest request.
+func IdempotencyInFlightTimeout() time.Duration {
+	return durationFromEnv("IDEMPOTENCY_IN_FLIGHT_TIMEOUT", 5*time.Minute)
+}
@@ -0,0 +1,10 @@
+package config
+
+import "time"
+
+// InterestRunInterval is how often the interest engine checks for days to
+// accrue and months to capitalize. It is read from INTEREST_RUN_INTERVAL
+// (e.g. "1h", "15m").
+func InterestRunInterval() time.Duration {
+	return durationFromEnv("INTEREST_RUN_INTERVAL", time.Hour)
+}
@@ -1,18 +1,139 @@
 package config
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-var JWTSecret = []byte("your_secret_key")
+var (
+	AccessTokenTTL  = durationFromEnv("ACCESS_TOKEN_TTL", 15*time.Minute)
+	RefreshTokenTTL = durationFromEnv("REFRESH_TOKEN_TTL", 30*24*time.Hour)
+)
+
+// GenerateJWT issues a short-lived access token bound to a login session.
+// authTime and acr say when and how the user last authenticated; they are
+// omitted for sessions that predate them.
+func GenerateJWT(userID, sessionID uint, role string, authTime *time.Time, acr string) (string, error) {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"user_id": userID,
+		"sid":     sessionID,
+		"role":    role,
+		"iat":     now.Unix(),
+		"exp":     now.Add(AccessTokenTTL).Unix(),
+	}
+	if authTime != nil {
+		claims["auth_time"] = authTime.Unix()
+		claims["acr"] = acr
+	}
+	return JWTKeys.Sign(claims)
+}
+
+// MFAChallengeTTL is how long a user has to enter their TOTP code after
+// passing the password step.
+const MFAChallengeTTL = 5 * time.Minute
 
-func GenerateJWT(userID uint) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+const mfaChallengePurpose = "mfa_challenge"
+
+// GenerateMFAChallenge issues the token returned by the password step of a
+// two-step login. It carries no session, so it is not accepted as an access
+// token.
+func GenerateMFAChallenge(userID uint) (string, error) {
+	now := time.Now()
+	return JWTKeys.Sign(jwt.MapClaims{
 		"user_id": userID,
-		"exp":     time.Now().Add(time.Hour * 72).Unix(),
+		"purpose": mfaChallengePurpose,
+		"iat":     now.Unix(),
+		"exp":     now.Add(MFAChallengeTTL).Unix(),
 	})
+}
+
+// ParseMFAChallenge verifies a challenge token and returns its user ID.
+func ParseMFAChallenge(tokenString string) (uint, error) {
+	token, err := JWTKeys.Parse(tokenString)
+	if err != nil {
+		return 0, err
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || claims["purpose"] != mfaChallengePurpose {
+		return 0, errors.New("not an MFA challenge token")
+	}
+	userID, ok := claims["user_id"].(float64)
+	if !ok {
+		return 0, errors.New("not an MFA challenge token")
+	}
+	return uint(userID), nil
+}
+
+// apiKeyPrefix marks API keys so they are easy to recognise, e.g. by secret
+// scanners.
+const apiKeyPrefix = "bk_"
+
+// GenerateAPIKey returns a new API key of the form bk_<prefix>.<secret>, the
+// prefix that identifies it and the hash under which it is stored.
+func GenerateAPIKey() (key, prefix, hash string, err error) {
+	id := make([]byte, 6)
+	if _, err := rand.Read(id); err != nil {
+		return "", "", "", err
+	}
+	secret, _, err := GenerateToken()
+	if err != nil {
+		return "", "", "", err
+	}
+	prefix = hex.EncodeToString(id)
+	key = apiKeyPrefix + prefix + "." + secret
+	return key, prefix, HashToken(key), nil
+}
+
+// APIKeyPrefix extracts the identifying prefix from an API key.
+func APIKeyPrefix(key string) (string, bool) {
+	rest, ok := strings.CutPrefix(key, apiKeyPrefix)
+	if !ok {
+		return "", false
+	}
+	prefix, secret, ok := strings.Cut(rest, ".")
+	if !ok || prefix == "" || secret == "" {
+		return "", false
+	}
+	return prefix, true
+}
+
+// GenerateToken returns a random opaque token, used for refresh tokens and
+// emailed links, and the hash under which it is stored.
+func GenerateToken() (token, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, HashToken(token), nil
+}
+
+// HashToken returns the hex SHA-256 of an opaque token.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func durationFromEnv(name string, fallback time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
 
-	return token.SignedString(JWTSecret)
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		log.Printf("Invalid %s %q, using %s\n", name, value, fallback)
+		return fallback
+	}
+	return d
 }
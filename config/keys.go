@@ -0,0 +1,224 @@
+package config
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWTKey is one key used to sign or verify access tokens. Private is nil for
+// keys that are only trusted for verification, e.g. a retired signing key
+// that stays around until the tokens it signed have expired.
+type JWTKey struct {
+	ID      string
+	Method  jwt.SigningMethod
+	Private interface{}
+	Public  interface{}
+}
+
+// JWTKeySet holds the key new tokens are signed with and every key a token
+// may be verified with, indexed by kid.
+type JWTKeySet struct {
+	Signing *JWTKey
+	Verify  map[string]*JWTKey
+}
+
+var JWTKeys *JWTKeySet
+
+// LoadJWTKeys reads signing keys from the environment:
+//
+//	JWT_KEYS_DIR     directory of PEM files named <kid>.pem. Private RSA or
+//	                 P-256 EC keys can sign (RS256/ES256); public keys only
+//	                 verify
+//	JWT_SIGNING_KID  the kid in JWT_KEYS_DIR used to sign new tokens
+//	JWT_SECRET       an HS256 shared secret, used only when no key directory
+//	                 is configured
+//	JWT_EPHEMERAL_KEY=true
+//	                 generate a throwaway ES256 key, for local development
+//
+// The ephemeral key's tokens stop working when the process restarts and do
+// not verify on other replicas, so with no keys configured and no explicit
+// opt-in, loading fails.
+func LoadJWTKeys() error {
+	keys := &JWTKeySet{Verify: map[string]*JWTKey{}}
+
+	switch {
+	case os.Getenv("JWT_KEYS_DIR") != "":
+		if err := keys.loadDir(os.Getenv("JWT_KEYS_DIR"), os.Getenv("JWT_SIGNING_KID")); err != nil {
+			return err
+		}
+	case os.Getenv("JWT_SECRET") != "":
+		keys.add(&JWTKey{
+			ID:      "default",
+			Method:  jwt.SigningMethodHS256,
+			Private: []byte(os.Getenv("JWT_SECRET")),
+			Public:  []byte(os.Getenv("JWT_SECRET")),
+		}, true)
+	case os.Getenv("JWT_EPHEMERAL_KEY") == "true":
+		log.Println("JWT_EPHEMERAL_KEY=true, generating an ephemeral ES256 key")
+		private, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return err
+		}
+		keys.add(&JWTKey{ID: "ephemeral", Method: jwt.SigningMethodES256, Private: private, Public: &private.PublicKey}, true)
+	default:
+		return errors.New("no JWT keys configured: set JWT_KEYS_DIR or JWT_SECRET, or JWT_EPHEMERAL_KEY=true for local development")
+	}
+
+	JWTKeys = keys
+	return nil
+}
+
+func (s *JWTKeySet) add(key *JWTKey, signing bool) {
+	s.Verify[key.ID] = key
+	if signing {
+		s.Signing = key
+	}
+}
+
+func (s *JWTKeySet) loadDir(dir, signingKID string) error {
+	if signingKID == "" {
+		return errors.New("JWT_SIGNING_KID must be set when JWT_KEYS_DIR is")
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		key, err := readPEMKey(path)
+		if err != nil {
+			return fmt.Errorf("load JWT key %s: %w", path, err)
+		}
+		s.add(key, key.ID == signingKID)
+	}
+
+	if s.Signing == nil || s.Signing.Private == nil {
+		return fmt.Errorf("no private key for JWT_SIGNING_KID %q in %s", signingKID, dir)
+	}
+	return nil
+}
+
+func readPEMKey(path string) (*JWTKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data")
+	}
+
+	key := &JWTKey{ID: strings.TrimSuffix(filepath.Base(path), ".pem")}
+	switch block.Type {
+	case "PUBLIC KEY":
+		key.Public, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key.Private, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key.Private, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key.Private, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch private := key.Private.(type) {
+	case *rsa.PrivateKey:
+		key.Public = &private.PublicKey
+	case *ecdsa.PrivateKey:
+		key.Public = &private.PublicKey
+	case nil:
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T", private)
+	}
+
+	switch public := key.Public.(type) {
+	case *rsa.PublicKey:
+		key.Method = jwt.SigningMethodRS256
+	case *ecdsa.PublicKey:
+		if public.Curve != elliptic.P256() {
+			return nil, errors.New("only P-256 EC keys are supported")
+		}
+		key.Method = jwt.SigningMethodES256
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T", public)
+	}
+	return key, nil
+}
+
+// Sign signs claims with the current signing key and names it in the kid
+// header.
+func (s *JWTKeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(s.Signing.Method, claims)
+	token.Header["kid"] = s.Signing.ID
+	return token.SignedString(s.Signing.Private)
+}
+
+// Parse verifies a token against the key named by its kid header. The
+// token's alg must be the one that key was configured with, so an attacker
+// cannot switch a token to "none" or to HS256 keyed with a public key.
+func (s *JWTKeySet) Parse(tokenString string) (*jwt.Token, error) {
+	methods := map[string]bool{}
+	for _, key := range s.Verify {
+		methods[key.Method.Alg()] = true
+	}
+	valid := make([]string, 0, len(methods))
+	for alg := range methods {
+		valid = append(valid, alg)
+	}
+
+	return jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := s.Verify[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+		if token.Method.Alg() != key.Method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method %s for key %q", token.Method.Alg(), kid)
+		}
+		return key.Public, nil
+	}, jwt.WithValidMethods(valid), jwt.WithExpirationRequired())
+}
+
+// JWKS returns the public verification keys as a JSON Web Key Set. Shared
+// HS256 secrets are never published.
+func (s *JWTKeySet) JWKS() map[string]interface{} {
+	keys := []map[string]string{}
+	for _, key := range s.Verify {
+		jwk := map[string]string{"kid": key.ID, "use": "sig", "alg": key.Method.Alg()}
+		switch public := key.Public.(type) {
+		case *rsa.PublicKey:
+			jwk["kty"] = "RSA"
+			jwk["n"] = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk["e"] = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		case *ecdsa.PublicKey:
+			jwk["kty"] = "EC"
+			jwk["crv"] = "P-256"
+			jwk["x"] = base64.RawURLEncoding.EncodeToString(public.X.FillBytes(make([]byte, 32)))
+			jwk["y"] = base64.RawURLEncoding.EncodeToString(public.Y.FillBytes(make([]byte, 32)))
+		default:
+			continue
+		}
+		keys = append(keys, jwk)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i]["kid"] < keys[j]["kid"] })
+	return map[string]interface{}{"keys": keys}
+}
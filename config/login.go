@@ -0,0 +1,86 @@
+package config
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LoginPolicy controls how failed logins are throttled.
+type LoginPolicy struct {
+	// Failures before an account or IP address is locked out
+	MaxAccountFailures int
+	MaxIPFailures      int
+	// How long a lockout lasts
+	LockoutDuration time.Duration
+	// Failures older than this are forgotten
+	FailureWindow time.Duration
+	// After each failure the next attempt must wait BaseBackoff, doubling
+	// per failure up to MaxBackoff
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+}
+
+// LoadLoginPolicy reads the policy from the environment:
+//
+//	LOGIN_MAX_FAILURES       failures per account before lockout (default 5)
+//	LOGIN_IP_MAX_FAILURES    failures per IP address before lockout (default 20)
+//	LOGIN_LOCKOUT_DURATION   length of a lockout (default 15m)
+//	LOGIN_FAILURE_WINDOW     how long failures are remembered (default 15m)
+//	LOGIN_BASE_BACKOFF       wait after the first failure (default 1s)
+//	LOGIN_MAX_BACKOFF        longest wait between failures (default 30s)
+func LoadLoginPolicy() LoginPolicy {
+	return LoginPolicy{
+		MaxAccountFailures: intFromEnv("LOGIN_MAX_FAILURES", 5),
+		MaxIPFailures:      intFromEnv("LOGIN_IP_MAX_FAILURES", 20),
+		LockoutDuration:    durationFromEnv("LOGIN_LOCKOUT_DURATION", 15*time.Minute),
+		FailureWindow:      durationFromEnv("LOGIN_FAILURE_WINDOW", 15*time.Minute),
+		BaseBackoff:        durationFromEnv("LOGIN_BASE_BACKOFF", time.Second),
+		MaxBackoff:         durationFromEnv("LOGIN_MAX_BACKOFF", 30*time.Second),
+	}
+}
+
+// TrustedProxies lists the proxy addresses or CIDRs allowed to set
+// X-Forwarded-For, read from the comma-separated TRUSTED_PROXIES. With none
+// configured the connecting address is always the client IP.
+func TrustedProxies() []string {
+	var proxies []string
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}
+
+func intFromEnv(name string, fallback int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		log.Printf("Invalid %s %q, using %d\n", name, value, fallback)
+		return fallback
+	}
+	return n
+}
+
+// nonNegativeIntFromEnv is intFromEnv for settings where zero is meaningful,
+// such as a spread that can be turned off.
+func nonNegativeIntFromEnv(name string, fallback int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		log.Printf("Invalid %s %q, using %d\n", name, value, fallback)
+		return fallback
+	}
+	return n
+}
@@ -0,0 +1,9 @@
+package config
+
+import "time"
+
+// OutboxRetention is how long published events stay in the outbox before
+// they are purged. It is read from OUTBOX_RETENTION (e.g. "168h").
+func OutboxRetention() time.Duration {
+	return durationFromEnv("OUTBOX_RETENTION", 7*24*time.Hour)
+}
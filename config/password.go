@@ -0,0 +1,42 @@
+package config
+
+import (
+	"bank-app/password"
+	"log"
+	"os"
+	"sync"
+)
+
+// LoadPasswordPolicy reads the password policy from the environment:
+//
+//	PASSWORD_MIN_LENGTH        minimum length (default 8)
+//	PASSWORD_REQUIRED_CLASSES  character classes required, 1-4 (default 3)
+//	PASSWORD_HISTORY           previous passwords that may not be reused, counting
+//	                           the current one; 0 allows reuse (default 5)
+//	PASSWORD_BLOCKLIST_PATH    file of extra passwords to reject
+func LoadPasswordPolicy() password.Policy {
+	policy := password.Policy{
+		MinLength:       intFromEnv("PASSWORD_MIN_LENGTH", 8),
+		RequiredClasses: intFromEnv("PASSWORD_REQUIRED_CLASSES", 3),
+		HistorySize:     nonNegativeIntFromEnv("PASSWORD_HISTORY", 5),
+	}
+	if policy.RequiredClasses > 4 {
+		policy.RequiredClasses = 4
+	}
+
+	policy.Blocklist = passwordBlocklist()
+	return policy
+}
+
+// passwordBlocklist reads PASSWORD_BLOCKLIST_PATH once; the file can be large.
+var passwordBlocklist = sync.OnceValue(func() map[string]bool {
+	path := os.Getenv("PASSWORD_BLOCKLIST_PATH")
+	if path == "" {
+		return nil
+	}
+	blocklist, err := password.LoadBlocklist(path)
+	if err != nil {
+		log.Printf("Failed to load PASSWORD_BLOCKLIST_PATH %q: %v\n", path, err)
+	}
+	return blocklist
+})
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"bank-app/fx"
+	"bank-app/money"
+	"log"
+	"os"
+	"time"
+)
+
+// Authentication context class references recorded in the acr claim.
+const (
+	ACRPassword = "pwd"
+	ACRMFA      = "mfa"
+)
+
+// StepUpThreshold is the largest withdrawal or transfer in currency, fees
+// included, allowed without a recent re-authentication.
+// STEP_UP_THRESHOLD_<CURRENCY> (e.g. STEP_UP_THRESHOLD_LKR="300000.00") sets
+// it for one currency. Otherwise
+// STEP_UP_THRESHOLD, which is in the default currency, is converted at the
+// mid-market rate; with no rate available the threshold is zero and every
+// amount needs a recent authentication.
+func StepUpThreshold(currency money.Currency) money.Amount {
+	if amount, ok := thresholdFromEnv("STEP_UP_THRESHOLD_"+string(currency), 0); ok {
+		return amount
+	}
+
+	base, _ := thresholdFromEnv("STEP_UP_THRESHOLD", money.FromMinor(100000))
+	if currency == money.DefaultCurrency {
+		return base
+	}
+	if FXRates == nil {
+		return 0
+	}
+	rate, err := FXRates.Rate(money.DefaultCurrency, currency)
+	if err != nil {
+		return 0
+	}
+	converted, err := fx.Convert(base, rate, 0)
+	if err != nil {
+		return 0
+	}
+	return converted.Mid
+}
+
+// thresholdFromEnv parses an amount from name, reporting whether it was set
+// and valid.
+func thresholdFromEnv(name string, fallback money.Amount) (money.Amount, bool) {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback, false
+	}
+
+	amount, err := money.Parse(value)
+	if err != nil || amount.IsNegative() {
+		log.Printf("Invalid %s %q, using %s\n", name, value, fallback)
+		return fallback, false
+	}
+	return amount, true
+}
+
+// StepUpMaxAge is how recently the user must have authenticated for a
+// high-value operation to go through. It is read from STEP_UP_MAX_AGE.
+func StepUpMaxAge() time.Duration {
+	return durationFromEnv("STEP_UP_MAX_AGE", 5*time.Minute)
+}
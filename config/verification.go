@@ -0,0 +1,50 @@
+package config
+
+import (
+	"os"
+	"strings"
+	"time"
+)
+
+// EmailVerificationTTL is how long an email verification link stays valid,
+// read from EMAIL_VERIFICATION_TTL.
+func EmailVerificationTTL() time.Duration {
+	return durationFromEnv("EMAIL_VERIFICATION_TTL", 48*time.Hour)
+}
+
+// PasswordResetTTL is how long a password reset link stays valid, read from
+// PASSWORD_RESET_TTL.
+func PasswordResetTTL() time.Duration {
+	return durationFromEnv("PASSWORD_RESET_TTL", time.Hour)
+}
+
+// LoadEmailRequestPolicy reads how requests for verification and password
+// reset emails are throttled, reusing the login throttle with requests in
+// place of failures:
+//
+//	EMAIL_MAX_REQUESTS         requests per address before lockout (default 3)
+//	EMAIL_IP_MAX_REQUESTS      requests per IP address before lockout (default 20)
+//	EMAIL_LOCKOUT_DURATION     length of a lockout (default 1h)
+//	EMAIL_REQUEST_WINDOW       how long requests are remembered (default 1h)
+//	EMAIL_BASE_BACKOFF         wait after the first request (default 30s)
+//	EMAIL_MAX_BACKOFF          longest wait between requests (default 5m)
+func LoadEmailRequestPolicy() LoginPolicy {
+	return LoginPolicy{
+		MaxAccountFailures: intFromEnv("EMAIL_MAX_REQUESTS", 3),
+		MaxIPFailures:      intFromEnv("EMAIL_IP_MAX_REQUESTS", 20),
+		LockoutDuration:    durationFromEnv("EMAIL_LOCKOUT_DURATION", time.Hour),
+		FailureWindow:      durationFromEnv("EMAIL_REQUEST_WINDOW", time.Hour),
+		BaseBackoff:        durationFromEnv("EMAIL_BASE_BACKOFF", 30*time.Second),
+		MaxBackoff:         durationFromEnv("EMAIL_MAX_BACKOFF", 5*time.Minute),
+	}
+}
+
+// AppURL builds a link into the web app from APP_BASE_URL, for use in
+// emails.
+func AppURL(path string) string {
+	base := os.Getenv("APP_BASE_URL")
+	if base == "" {
+		base = "http://localhost:8080"
+	}
+	return strings.TrimSuffix(base, "/") + path
+}
@@ -0,0 +1,67 @@
+package events
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Envelope wraps every event published by the API. Consumers should switch
+// on Type and Version before decoding Data.
+type Envelope struct {
+	EventID       string          `json:"event_id"`
+	Type          string          `json:"type"`
+	Version       int             `json:"version"`
+	OccurredAt    time.Time       `json:"occurred_at"`
+	CorrelationID string          `json:"correlation_id,omitempty"`
+	Data          json.RawMessage `json:"data"`
+}
+
+// Event is implemented by every typed event payload.
+type Event interface {
+	EventType() string
+	EventVersion() int
+}
+
+// New wraps event in an envelope with a fresh event ID.
+func New(event Event, correlationID string) (Envelope, error) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return Envelope{}, err
+	}
+
+	return Envelope{
+		EventID:       NewID(),
+		Type:          event.EventType(),
+		Version:       event.EventVersion(),
+		OccurredAt:    time.Now().UTC(),
+		CorrelationID: correlationID,
+		Data:          data,
+	}, nil
+}
+
+// Decode unmarshals the envelope's data into its typed event.
+func (e Envelope) Decode() (Event, error) {
+	factory, ok := registry[registryKey(e.Type, e.Version)]
+	if !ok {
+		return nil, fmt.Errorf("events: unknown event %s v%d", e.Type, e.Version)
+	}
+
+	event := factory()
+	if err := json.Unmarshal(e.Data, event); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// NewID returns a random RFC 4122 version 4 UUID.
+func NewID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
@@ -0,0 +1,97 @@
+package events
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+)
+
+// EventPublisher delivers event envelopes to downstream consumers.
+type EventPublisher interface {
+	Publish(envelope Envelope) error
+	Close() error
+}
+
+// HealthReporter is implemented by publishers backed by a connection that
+// can go down. Health returns nil while events can be delivered.
+type HealthReporter interface {
+	Health() error
+}
+
+// DiscardPublisher accepts every event and keeps none of them, for running
+// the API locally without a broker.
+type DiscardPublisher struct{}
+
+func (DiscardPublisher) Publish(Envelope) error { return nil }
+func (DiscardPublisher) Close() error           { return nil }
+
+// MemoryPublisher keeps every published event in memory so tests can
+// inspect them. Nothing is ever dropped, so it is not meant for a long-running
+// server.
+type MemoryPublisher struct {
+	mu        sync.Mutex
+	published []Envelope
+}
+
+func NewMemoryPublisher() *MemoryPublisher {
+	return &MemoryPublisher{}
+}
+
+func (p *MemoryPublisher) Publish(envelope Envelope) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.published = append(p.published, envelope)
+	return nil
+}
+
+// Published returns a copy of every event published so far.
+func (p *MemoryPublisher) Published() []Envelope {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]Envelope(nil), p.published...)
+}
+
+func (p *MemoryPublisher) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.published = nil
+}
+
+func (p *MemoryPublisher) Close() error {
+	return nil
+}
+
+// FilePublisher appends each event as one JSON line to a file.
+type FilePublisher struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+func NewFilePublisher(path string) (*FilePublisher, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &FilePublisher{file: file}, nil
+}
+
+func (p *FilePublisher) Publish(envelope Envelope) error {
+	line, err := json.Marshal(envelope)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, err := p.file.Write(line); err != nil {
+		return err
+	}
+	return p.file.Sync()
+}
+
+func (p *FilePublisher) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.file.Close()
+}
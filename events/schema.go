@@ -0,0 +1,19 @@
+package events
+
+import (
+	"embed"
+	"fmt"
+)
+
+//go:embed schemas/*.json
+var schemaFiles embed.FS
+
+// Schema returns the JSON Schema describing the envelope of an event type and
+// version, for consumers that validate what they receive.
+func Schema(eventType string, version int) ([]byte, error) {
+	data, err := schemaFiles.ReadFile(fmt.Sprintf("schemas/%s.json", registryKey(eventType, version)))
+	if err != nil {
+		return nil, fmt.Errorf("events: no schema for %s v%d", eventType, version)
+	}
+	return data, nil
+}
@@ -0,0 +1,283 @@
+package events
+
+import (
+	"bank-app/money"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden payloads in testdata")
+
+var fixedTime = time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+// samples holds one fully populated event per registered type and version.
+// Adding an event to the registry without a sample fails TestEveryEventHasSchemaAndSample.
+var samples = map[string]Event{
+	registryKey(TypeDepositSucceeded, 1): &DepositSucceeded{
+		TransactionID: 42, UserID: 7, AccountNo: "123456789",
+		Amount: money.FromMinor(2500), Currency: "USD", Balance: money.FromMinor(10025), ToEmail: "jane@example.com",
+	},
+	registryKey(TypeWithdrawalSucceeded, 1): &WithdrawalSucceeded{
+		TransactionID: 43, UserID: 7, AccountNo: "123456789",
+		Amount: money.FromMinor(1000), Currency: "USD", Balance: money.FromMinor(9025), ToEmail: "jane@example.com",
+	},
+	registryKey(TypeTransferSent, 1): &TransferSent{
+		TransactionID: 44, UserID: 7, FromAccountNo: "123456789", ToAccountNo: "987654321",
+		Amount: money.FromMinor(5000), Currency: "USD", ToEmail: "jane@example.com",
+	},
+	registryKey(TypeTransferReceived, 1): &TransferReceived{
+		TransactionID: 45, UserID: 8, FromAccountNo: "123456789", ToAccountNo: "987654321",
+		Amount: money.FromMinor(4600), Currency: "EUR", ToEmail: "john@example.com",
+	},
+	registryKey(TypeAccountLocked, 1): &AccountLocked{
+		UserID: 7, Failures: 5, LockedUntil: fixedTime, IPAddress: "203.0.113.9", ToEmail: "jane@example.com",
+	},
+	registryKey(TypeIPLocked, 1): &IPLocked{
+		IPAddress: "203.0.113.9", Failures: 20, LockedUntil: fixedTime,
+	},
+	registryKey(TypeEmailVerificationRequested, 1): &EmailVerificationRequested{
+		UserID: 7, FirstName: "Jane", Link: "https://bank.example.com/verify-email?token=abc",
+		ExpiresAt: fixedTime, ToEmail: "jane@example.com",
+	},
+	registryKey(TypePasswordResetRequested, 1): &PasswordResetRequested{
+		UserID: 7, FirstName: "Jane", Link: "https://bank.example.com/reset-password?token=abc",
+		ExpiresAt: fixedTime, ToEmail: "jane@example.com",
+	},
+}
+
+func sampleEnvelope(t *testing.T, event Event) map[string]interface{} {
+	t.Helper()
+	envelope, err := New(event, "corr-1")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	envelope.EventID = "0b8e5a52-3f0c-4d7e-9a8b-2c1d0e9f8a7b"
+	envelope.OccurredAt = fixedTime
+
+	raw, err := json.Marshal(envelope)
+	if err != nil {
+		t.Fatalf("marshalling envelope: %v", err)
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		t.Fatalf("unmarshalling envelope: %v", err)
+	}
+	return doc
+}
+
+func loadSchema(t *testing.T, eventType string, version int) map[string]interface{} {
+	t.Helper()
+	raw, err := Schema(eventType, version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schema map[string]interface{}
+	if err := json.Unmarshal(raw, &schema); err != nil {
+		t.Fatalf("schema for %s v%d is not valid JSON: %v", eventType, version, err)
+	}
+	return schema
+}
+
+func TestEveryEventHasSchemaAndSample(t *testing.T) {
+	for key := range registry {
+		if _, ok := samples[key]; !ok {
+			t.Errorf("%s has no sample in samples", key)
+		}
+		if _, err := schemaFiles.ReadFile("schemas/" + key + ".json"); err != nil {
+			t.Errorf("%s has no schema", key)
+		}
+	}
+
+	files, err := schemaFiles.ReadDir("schemas")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		if _, ok := registry[strings.TrimSuffix(file.Name(), ".json")]; !ok {
+			t.Errorf("schema %s has no registered event", file.Name())
+		}
+	}
+}
+
+func TestEventsValidateAgainstSchemas(t *testing.T) {
+	for key, event := range samples {
+		t.Run(key, func(t *testing.T) {
+			schema := loadSchema(t, event.EventType(), event.EventVersion())
+			for _, problem := range validate(schema, sampleEnvelope(t, event), "$") {
+				t.Error(problem)
+			}
+		})
+	}
+}
+
+// TestSchemasMatchStructs catches a field added to or renamed in a Go struct
+// without the schema following, and the reverse.
+func TestSchemasMatchStructs(t *testing.T) {
+	for key, event := range samples {
+		t.Run(key, func(t *testing.T) {
+			schema := loadSchema(t, event.EventType(), event.EventVersion())
+			data := schema["properties"].(map[string]interface{})["data"].(map[string]interface{})
+			properties := data["properties"].(map[string]interface{})
+
+			fields := map[string]bool{}
+			structType := reflect.TypeOf(event).Elem()
+			for i := 0; i < structType.NumField(); i++ {
+				name := strings.Split(structType.Field(i).Tag.Get("json"), ",")[0]
+				fields[name] = true
+				if _, ok := properties[name]; !ok {
+					t.Errorf("field %q is not described by the schema", name)
+				}
+			}
+			for name := range properties {
+				if !fields[name] {
+					t.Errorf("schema property %q has no struct field", name)
+				}
+			}
+			for _, name := range data["required"].([]interface{}) {
+				if !fields[name.(string)] {
+					t.Errorf("schema requires %q, which the struct does not have", name)
+				}
+			}
+		})
+	}
+}
+
+// TestGoldenPayloads pins the v1 payloads. A change here breaks consumers:
+// publish a new version instead of editing the golden file. Run with -update
+// only when adding a new event or version.
+func TestGoldenPayloads(t *testing.T) {
+	for key, event := range samples {
+		t.Run(key, func(t *testing.T) {
+			got, err := json.MarshalIndent(event, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, '\n')
+
+			path := filepath.Join("testdata", key+".json")
+			if *update {
+				if err := os.WriteFile(path, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("reading golden payload: %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("payload changed from %s:\n got: %s\nwant: %s", path, got, want)
+			}
+
+			// Payloads already on the wire must still decode to the same event
+			envelope := Envelope{Type: event.EventType(), Version: event.EventVersion(), Data: want}
+			decoded, err := envelope.Decode()
+			if err != nil {
+				t.Fatalf("decoding golden payload: %v", err)
+			}
+			if !reflect.DeepEqual(decoded, event) {
+				t.Errorf("golden payload decoded to %+v, want %+v", decoded, event)
+			}
+		})
+	}
+}
+
+// validate checks doc against the subset of JSON Schema the event schemas
+// use: type, const, required, properties, additionalProperties, pattern,
+// minimum and format.
+func validate(schema map[string]interface{}, doc interface{}, path string) []string {
+	var problems []string
+	fail := func(format string, args ...interface{}) {
+		problems = append(problems, path+": "+fmt.Sprintf(format, args...))
+	}
+
+	if want, ok := schema["const"]; ok && !reflect.DeepEqual(want, doc) {
+		fail("is %v, want %v", doc, want)
+	}
+
+	switch schema["type"] {
+	case "object":
+		object, ok := doc.(map[string]interface{})
+		if !ok {
+			fail("is not an object")
+			return problems
+		}
+		properties, _ := schema["properties"].(map[string]interface{})
+		required, _ := schema["required"].([]interface{})
+		for _, name := range required {
+			if _, ok := object[name.(string)]; !ok {
+				fail("missing required %q", name)
+			}
+		}
+		names := make([]string, 0, len(object))
+		for name := range object {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			property, ok := properties[name].(map[string]interface{})
+			if !ok {
+				if schema["additionalProperties"] == false {
+					fail("unexpected property %q", name)
+				}
+				continue
+			}
+			problems = append(problems, validate(property, object[name], path+"."+name)...)
+		}
+	case "string":
+		s, ok := doc.(string)
+		if !ok {
+			fail("is not a string")
+			return problems
+		}
+		if pattern, ok := schema["pattern"].(string); ok && !regexp.MustCompile(pattern).MatchString(s) {
+			fail("%q does not match %s", s, pattern)
+		}
+		if format, ok := schema["format"].(string); ok && !validFormat(format, s) {
+			fail("%q is not a valid %s", s, format)
+		}
+	case "integer":
+		n, ok := doc.(float64)
+		if !ok || n != float64(int64(n)) {
+			fail("is not an integer")
+			return problems
+		}
+		if minimum, ok := schema["minimum"].(float64); ok && n < minimum {
+			fail("%v is below the minimum %v", n, minimum)
+		}
+	case nil:
+	default:
+		fail("schema type %v is not supported by this validator", schema["type"])
+	}
+	return problems
+}
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+
+func validFormat(format, s string) bool {
+	switch format {
+	case "uuid":
+		return uuidPattern.MatchString(s)
+	case "date-time":
+		_, err := time.Parse(time.RFC3339Nano, s)
+		return err == nil
+	case "email":
+		_, err := mail.ParseAddress(s)
+		return err == nil
+	case "uri":
+		u, err := url.Parse(s)
+		return err == nil && u.Scheme != "" && u.Host != ""
+	}
+	return true
+}
@@ -0,0 +1,141 @@
+package events
+
+import (
+	"bank-app/money"
+	"fmt"
+	"time"
+)
+
+const (
+	TypeDepositSucceeded    = "account.deposit.succeeded"
+	TypeWithdrawalSucceeded = "account.withdrawal.succeeded"
+	TypeTransferSent        = "transfer.sent"
+	TypeTransferReceived    = "transfer.received"
+	TypeAccountLocked       = "security.account.locked"
+	TypeIPLocked            = "security.ip.locked"
+
+	TypeEmailVerificationRequested = "user.email_verification.requested"
+	TypePasswordResetRequested     = "user.password_reset.requested"
+)
+
+var registry = map[string]func() Event{
+	registryKey(TypeDepositSucceeded, 1):    func() Event { return &DepositSucceeded{} },
+	registryKey(TypeWithdrawalSucceeded, 1): func() Event { return &WithdrawalSucceeded{} },
+	registryKey(TypeTransferSent, 1):        func() Event { return &TransferSent{} },
+	registryKey(TypeTransferReceived, 1):    func() Event { return &TransferReceived{} },
+	registryKey(TypeAccountLocked, 1):       func() Event { return &AccountLocked{} },
+	registryKey(TypeIPLocked, 1):            func() Event { return &IPLocked{} },
+
+	registryKey(TypeEmailVerificationRequested, 1): func() Event { return &EmailVerificationRequested{} },
+	registryKey(TypePasswordResetRequested, 1):     func() Event { return &PasswordResetRequested{} },
+}
+
+func registryKey(eventType string, version int) string {
+	return fmt.Sprintf("%s.v%d", eventType, version)
+}
+
+// DepositSucceeded is published after money is deposited into an account.
+type DepositSucceeded struct {
+	TransactionID uint           `json:"transaction_id"`
+	UserID        uint           `json:"user_id"`
+	AccountNo     string         `json:"account_no"`
+	Amount        money.Amount   `json:"amount"`
+	Currency      money.Currency `json:"currency"`
+	Balance       money.Amount   `json:"balance"`
+	ToEmail       string         `json:"to_email"`
+}
+
+func (*DepositSucceeded) EventType() string { return TypeDepositSucceeded }
+func (*DepositSucceeded) EventVersion() int { return 1 }
+
+// WithdrawalSucceeded is published after money is withdrawn from an account.
+type WithdrawalSucceeded struct {
+	TransactionID uint           `json:"transaction_id"`
+	UserID        uint           `json:"user_id"`
+	AccountNo     string         `json:"account_no"`
+	Amount        money.Amount   `json:"amount"`
+	Currency      money.Currency `json:"currency"`
+	Balance       money.Amount   `json:"balance"`
+	ToEmail       string         `json:"to_email"`
+}
+
+func (*WithdrawalSucceeded) EventType() string { return TypeWithdrawalSucceeded }
+func (*WithdrawalSucceeded) EventVersion() int { return 1 }
+
+// TransferSent is published to the sender of a completed transfer.
+type TransferSent struct {
+	TransactionID uint           `json:"transaction_id"`
+	UserID        uint           `json:"user_id"`
+	FromAccountNo string         `json:"from_account_no"`
+	ToAccountNo   string         `json:"to_account_no"`
+	Amount        money.Amount   `json:"amount"`
+	Currency      money.Currency `json:"currency"`
+	ToEmail       string         `json:"to_email"`
+}
+
+func (*TransferSent) EventType() string { return TypeTransferSent }
+func (*TransferSent) EventVersion() int { return 1 }
+
+// TransferReceived is published to the receiver of a completed transfer.
+type TransferReceived struct {
+	TransactionID uint           `json:"transaction_id"`
+	UserID        uint           `json:"user_id"`
+	FromAccountNo string         `json:"from_account_no"`
+	ToAccountNo   string         `json:"to_account_no"`
+	Amount        money.Amount   `json:"amount"`
+	Currency      money.Currency `json:"currency"`
+	ToEmail       string         `json:"to_email"`
+}
+
+func (*TransferReceived) EventType() string { return TypeTransferReceived }
+func (*TransferReceived) EventVersion() int { return 1 }
+
+// AccountLocked is published when repeated failed logins lock a user out.
+type AccountLocked struct {
+	UserID      uint      `json:"user_id"`
+	Failures    int       `json:"failures"`
+	LockedUntil time.Time `json:"locked_until"`
+	IPAddress   string    `json:"ip_address"`
+	ToEmail     string    `json:"to_email"`
+}
+
+func (*AccountLocked) EventType() string { return TypeAccountLocked }
+func (*AccountLocked) EventVersion() int { return 1 }
+
+// IPLocked is published when repeated failed logins from one address block
+// it from logging in. It has no recipient and is meant for monitoring.
+type IPLocked struct {
+	IPAddress   string    `json:"ip_address"`
+	Failures    int       `json:"failures"`
+	LockedUntil time.Time `json:"locked_until"`
+}
+
+func (*IPLocked) EventType() string { return TypeIPLocked }
+func (*IPLocked) EventVersion() int { return 1 }
+
+// EmailVerificationRequested carries a link that proves the user owns their
+// email address. Link contains a secret token, so the event must only reach
+// the notifier.
+type EmailVerificationRequested struct {
+	UserID    uint      `json:"user_id"`
+	FirstName string    `json:"first_name"`
+	Link      string    `json:"link"`
+	ExpiresAt time.Time `json:"expires_at"`
+	ToEmail   string    `json:"to_email"`
+}
+
+func (*EmailVerificationRequested) EventType() string { return TypeEmailVerificationRequested }
+func (*EmailVerificationRequested) EventVersion() int { return 1 }
+
+// PasswordResetRequested carries a link that lets the user choose a new
+// password. Like EmailVerificationRequested, Link is secret.
+type PasswordResetRequested struct {
+	UserID    uint      `json:"user_id"`
+	FirstName string    `json:"first_name"`
+	Link      string    `json:"link"`
+	ExpiresAt time.Time `json:"expires_at"`
+	ToEmail   string    `json:"to_email"`
+}
+
+func (*PasswordResetRequested) EventType() string { return TypePasswordResetRequested }
+func (*PasswordResetRequested) EventVersion() int { return 1 }
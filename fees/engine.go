@@ -0,0 +1,172 @@
+package fees
+
+import (
+	"bank-app/catalog"
+	"bank-app/ledger"
+	"bank-app/lifecycle"
+	"bank-app/models"
+	"bank-app/money"
+	"context"
+	"log"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Engine assesses the monthly maintenance and minimum balance fees once
+// each calendar month is over. The month an account is first seen in is
+// never charged. Accounts are locked while they are processed, so several
+// instances can run at once.
+type Engine struct {
+	DB       *gorm.DB
+	Interval time.Duration
+}
+
+func NewEngine(db *gorm.DB) *Engine {
+	return &Engine{DB: db, Interval: time.Hour}
+}
+
+// Run processes accounts until ctx is cancelled.
+func (e *Engine) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := e.RunOnce(time.Now()); err != nil {
+			log.Printf("Fee run failed: %v\n", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce charges every complete month not yet charged. A failure on one
+// account is logged and does not stop the others.
+func (e *Engine) RunOnce(now time.Time) error {
+	var ids []uint
+	if err := e.DB.Model(&models.Account{}).Where("status <> ?", lifecycle.Closed).Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		if err := e.processAccount(id, now); err != nil {
+			log.Printf("Fee run failed for account %d: %v\n", id, err)
+		}
+	}
+	return nil
+}
+
+func (e *Engine) processAccount(id uint, now time.Time) error {
+	tx := e.DB.Begin()
+	var account models.Account
+	if err := tx.Set("gorm:query_option", "FOR UPDATE").First(&account, id).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if account.Status == lifecycle.Closed {
+		tx.Rollback()
+		return nil
+	}
+
+	if account.FeesChargedThrough == nil {
+		through := monthEnd(now)
+		if err := tx.Model(&account).Update("fees_charged_through", &through).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+		return tx.Commit().Error
+	}
+
+	for month := account.FeesChargedThrough.AddDate(0, 0, 1); monthEnd(month).AddDate(0, 0, 1).Before(now); month = month.AddDate(0, 1, 0) {
+		if err := chargeMonth(tx, &account, month); err != nil {
+			tx.Rollback()
+			return err
+		}
+		through := monthEnd(month)
+		if err := tx.Model(&account).Update("fees_charged_through", &through).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit().Error
+}
+
+// chargeMonth assesses the monthly fees for the month starting at month.
+// A fee larger than the balance takes only what is there, since accounts
+// cannot go overdrawn.
+func chargeMonth(tx *gorm.DB, account *models.Account, month time.Time) error {
+	productRules, err := rules(tx, account)
+	if err != nil {
+		return err
+	}
+	end := monthEnd(month)
+
+	var charges []models.FeeCharge
+	if rule := ruleFor(productRules, catalog.FeeMonthlyMaintenance); rule != nil {
+		charge, err := assess(tx, account, rule.Kind, rule.Amount, end)
+		if err != nil {
+			return err
+		}
+		charges = append(charges, charge)
+	}
+	if rule := ruleFor(productRules, catalog.FeeMinimumBalance); rule != nil {
+		low, err := lowestBalance(tx, account, month, end)
+		if err != nil {
+			return err
+		}
+		if low < rule.MinimumBalance {
+			charge, err := assess(tx, account, rule.Kind, rule.Amount, end)
+			if err != nil {
+				return err
+			}
+			charges = append(charges, charge)
+		}
+	}
+
+	for _, charge := range charges {
+		if err := tx.First(account, account.ID).Error; err != nil {
+			return err
+		}
+		if !charge.Waived && charge.Amount > account.Balance {
+			charge.Amount = account.Balance
+		}
+		if !charge.Waived && !charge.Amount.IsPositive() {
+			continue
+		}
+		if err := Post(tx, account, []models.FeeCharge{charge}, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lowestBalance returns the lowest end-of-day balance from start to end,
+// inclusive.
+func lowestBalance(tx *gorm.DB, account *models.Account, start, end time.Time) (money.Amount, error) {
+	customer, err := ledger.CustomerAccount(tx, account)
+	if err != nil {
+		return 0, err
+	}
+
+	var low money.Amount
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		balance, err := ledger.BalanceAt(tx, customer, day.AddDate(0, 0, 1))
+		if err != nil {
+			return 0, err
+		}
+		if day.Equal(start) || balance < low {
+			low = balance
+		}
+	}
+	return low, nil
+}
+
+// monthEnd returns the start of the last day of t's month.
+func monthEnd(t time.Time) time.Time {
+	t = t.Local()
+	return time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, time.Local)
+}
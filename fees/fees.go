@@ -0,0 +1,175 @@
+package fees
+
+import (
+	"bank-app/catalog"
+	"bank-app/ledger"
+	"bank-app/models"
+	"bank-app/money"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	StatusCharged = "success"
+	StatusWaived  = "waived"
+)
+
+// rules returns the fee rules of the account's product. Retired products
+// keep charging the accounts already on them. Fee amounts are in the
+// product's currency, so an account in any other, or on a product saved
+// without one, is charged nothing rather than the same number of its own
+// minor units.
+func rules(db *gorm.DB, account *models.Account) ([]models.FeeRule, error) {
+	var product models.AccountProduct
+	err := db.Unscoped().Preload("FeeRules").Where("code = ?", account.AccountType).First(&product).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return nil, nil
+	}
+	if err != nil || product.Currency != account.Currency {
+		return nil, err
+	}
+	return product.FeeRules, nil
+}
+
+func ruleFor(rules []models.FeeRule, kind string) *models.FeeRule {
+	for i := range rules {
+		if rules[i].Kind == kind {
+			return &rules[i]
+		}
+	}
+	return nil
+}
+
+// assess builds a charge, waived if a waiver for the account's owner or
+// product covers kind at time at.
+func assess(db *gorm.DB, account *models.Account, kind string, amount money.Amount, at time.Time) (models.FeeCharge, error) {
+	charge := models.FeeCharge{Kind: kind, Amount: amount}
+
+	var waiver models.FeeWaiver
+	err := db.Where("(user_id = ? OR product_code = ?) AND (kind = '' OR kind = ?) AND (expires_at IS NULL OR expires_at > ?)",
+		account.UserID, account.AccountType, kind, at).
+		Order("id").
+		First(&waiver).Error
+	if err == nil {
+		charge.Waived, charge.WaiverID = true, &waiver.ID
+		return charge, nil
+	}
+	if !gorm.IsRecordNotFoundError(err) {
+		return charge, err
+	}
+	return charge, nil
+}
+
+// ForTransfer returns the fees an outgoing transfer from account incurs.
+func ForTransfer(db *gorm.DB, account *models.Account, at time.Time) ([]models.FeeCharge, error) {
+	productRules, err := rules(db, account)
+	if err != nil {
+		return nil, err
+	}
+	rule := ruleFor(productRules, catalog.FeeTransfer)
+	if rule == nil {
+		return nil, nil
+	}
+	charge, err := assess(db, account, rule.Kind, rule.Amount, at)
+	if err != nil {
+		return nil, err
+	}
+	return []models.FeeCharge{charge}, nil
+}
+
+// ForWithdrawal returns the fees a withdrawal from account incurs. Each
+// withdrawal beyond the rule's free count in a calendar month is charged.
+func ForWithdrawal(db *gorm.DB, account *models.Account, at time.Time) ([]models.FeeCharge, error) {
+	productRules, err := rules(db, account)
+	if err != nil {
+		return nil, err
+	}
+	rule := ruleFor(productRules, catalog.FeeExcessWithdrawal)
+	if rule == nil {
+		return nil, nil
+	}
+
+	monthStart := time.Date(at.Year(), at.Month(), 1, 0, 0, 0, 0, at.Location())
+	var made int
+	if err := db.Model(&models.Transaction{}).
+		Where("account_id = ? AND transaction_type = ? AND transaction_date >= ?", account.ID, "withdrawal", monthStart).
+		Count(&made).Error; err != nil {
+		return nil, err
+	}
+	if made < rule.FreeCount {
+		return nil, nil
+	}
+
+	charge, err := assess(db, account, rule.Kind, rule.Amount, at)
+	if err != nil {
+		return nil, err
+	}
+	return []models.FeeCharge{charge}, nil
+}
+
+// Total adds up the charges that are not waived.
+func Total(charges []models.FeeCharge) (money.Amount, error) {
+	var total money.Amount
+	for _, charge := range charges {
+		if charge.Waived {
+			continue
+		}
+		var err error
+		if total, err = total.Add(charge.Amount); err != nil {
+			return 0, err
+		}
+	}
+	return total, nil
+}
+
+// Post takes each charge from a locked account as fee income and logs it as
+// a fee transaction linked to trigger, if there is one. Waived charges are
+// logged without moving money. The transaction IDs are filled in on charges.
+func Post(tx *gorm.DB, account *models.Account, charges []models.FeeCharge, trigger *models.Transaction) error {
+	for i := range charges {
+		charge := &charges[i]
+		transaction := models.Transaction{
+			TransactionType: "fee",
+			Amount:          charge.Amount,
+			Currency:        account.Currency,
+			AccountID:       account.ID,
+			Status:          StatusWaived,
+			FeeKind:         charge.Kind,
+			TransactionDate: time.Now(),
+		}
+		if trigger != nil {
+			transaction.RelatedTransactionID = &trigger.ID
+		}
+
+		if !charge.Waived {
+			customer, err := ledger.CustomerAccount(tx, account)
+			if err != nil {
+				return err
+			}
+			income, err := ledger.SystemAccount(tx, ledger.Fees, account.Currency)
+			if err != nil {
+				return err
+			}
+			journal, err := ledger.Post(tx, ledger.Entry{
+				Kind:        "fee",
+				Description: "Fee (" + charge.Kind + ") on " + account.AccountNo,
+				Lines: []ledger.Line{
+					ledger.DebitLine(customer, charge.Amount),
+					ledger.CreditLine(income, charge.Amount),
+				},
+			})
+			if err != nil {
+				return err
+			}
+			transaction.Status = StatusCharged
+			transaction.JournalEntryID = &journal.ID
+		}
+
+		if err := tx.Create(&transaction).Error; err != nil {
+			return err
+		}
+		charge.TransactionID = &transaction.ID
+	}
+	return nil
+}
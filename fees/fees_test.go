@@ -0,0 +1,346 @@
+package fees
+
+import (
+	"bank-app/catalog"
+	"bank-app/ledger"
+	"bank-app/lifecycle"
+	"bank-app/models"
+	"bank-app/money"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/mysql"
+)
+
+func TestRuleFor(t *testing.T) {
+	rules := []models.FeeRule{
+		{Kind: catalog.FeeTransfer, Amount: 100},
+		{Kind: catalog.FeeMonthlyMaintenance, Amount: 500},
+	}
+	if rule := ruleFor(rules, catalog.FeeMonthlyMaintenance); rule == nil || rule.Amount != 500 {
+		t.Errorf("maintenance rule = %v", rule)
+	}
+	if rule := ruleFor(rules, catalog.FeeExcessWithdrawal); rule != nil {
+		t.Errorf("missing kind found %v", rule)
+	}
+	if rule := ruleFor(nil, catalog.FeeTransfer); rule != nil {
+		t.Errorf("no rules found %v", rule)
+	}
+}
+
+func TestTotal(t *testing.T) {
+	charges := []models.FeeCharge{
+		{Kind: catalog.FeeTransfer, Amount: 150},
+		{Kind: catalog.FeeExcessWithdrawal, Amount: 200, Waived: true},
+		{Kind: catalog.FeeMonthlyMaintenance, Amount: 25},
+	}
+	if total, err := Total(charges); err != nil || total != 175 {
+		t.Errorf("Total = %s, %v; want 1.75 without the waived charge", total, err)
+	}
+	if total, err := Total(nil); err != nil || total != 0 {
+		t.Errorf("Total(nil) = %s, %v", total, err)
+	}
+
+	huge := []models.FeeCharge{{Amount: math.MaxInt64}, {Amount: 1}}
+	if _, err := Total(huge); !errors.Is(err, money.ErrOverflow) {
+		t.Errorf("overflowing total: got %v, want ErrOverflow", err)
+	}
+}
+
+func TestMonthEnd(t *testing.T) {
+	cases := []struct {
+		in   time.Time
+		want string
+	}{
+		{time.Date(2025, 1, 1, 0, 0, 0, 0, time.Local), "2025-01-31"},
+		{time.Date(2025, 1, 31, 23, 59, 0, 0, time.Local), "2025-01-31"},
+		{time.Date(2025, 2, 14, 12, 0, 0, 0, time.Local), "2025-02-28"},
+		{time.Date(2024, 2, 1, 0, 0, 0, 0, time.Local), "2024-02-29"},
+		{time.Date(2025, 4, 30, 0, 0, 0, 0, time.Local), "2025-04-30"},
+		{time.Date(2025, 12, 5, 0, 0, 0, 0, time.Local), "2025-12-31"},
+	}
+	for _, tc := range cases {
+		got := monthEnd(tc.in)
+		if got.Format(time.DateOnly) != tc.want || got.Hour() != 0 || got.Minute() != 0 {
+			t.Errorf("monthEnd(%s) = %s, want the start of %s", tc.in, got, tc.want)
+		}
+	}
+}
+
+// openTestDB connects to the MySQL database in TEST_MYSQL_DSN, or skips the
+// test when none is configured. Each test uses its own product and user IDs
+// so it does not see rows left by others.
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_MYSQL_DSN")
+	if dsn == "" {
+		t.Skip("TEST_MYSQL_DSN is not set, e.g. user:pass@tcp(localhost:3306)/bank_test?charset=utf8mb4&parseTime=True&loc=Local")
+	}
+
+	db, err := gorm.Open("mysql", dsn)
+	if err != nil {
+		t.Fatalf("connecting to test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if err := db.AutoMigrate(
+		&models.Account{},
+		&models.Transaction{},
+		&models.LedgerAccount{},
+		&models.JournalEntry{},
+		&models.Posting{},
+		&models.AccountProduct{},
+		&models.FeeRule{},
+		&models.FeeWaiver{},
+	).Error; err != nil {
+		t.Fatalf("migrating test database: %v", err)
+	}
+	return db
+}
+
+// testProduct creates a USD product with rules under a fresh code.
+func testProduct(t *testing.T, db *gorm.DB, rules ...models.FeeRule) models.AccountProduct {
+	t.Helper()
+	product := models.AccountProduct{
+		Code:             fmt.Sprintf("fees_%d", rand.Int63()),
+		Name:             "Fee test",
+		Currency:         money.USD,
+		InterestDayCount: catalog.DayCountActual365,
+		FeeRules:         rules,
+	}
+	if err := db.Create(&product).Error; err != nil {
+		t.Fatalf("creating product: %v", err)
+	}
+	return product
+}
+
+// testAccount opens an account on product for a fresh user, funded with
+// balance through the ledger.
+func testAccount(t *testing.T, db *gorm.DB, product models.AccountProduct, balance money.Amount) models.Account {
+	t.Helper()
+	account := models.Account{
+		UserID:      uint(rand.Int31()),
+		AccountNo:   fmt.Sprintf("%09d", rand.Intn(1_000_000_000)),
+		Currency:    product.Currency,
+		AccountType: product.Code,
+		Status:      lifecycle.Active,
+	}
+	tx := db.Begin()
+	defer tx.Rollback()
+	if err := tx.Create(&account).Error; err != nil {
+		t.Fatalf("creating account: %v", err)
+	}
+	if balance.IsPositive() {
+		cash, err := ledger.SystemAccount(tx, ledger.Cash, account.Currency)
+		if err != nil {
+			t.Fatal(err)
+		}
+		customer, err := ledger.CustomerAccount(tx, &account)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ledger.Post(tx, ledger.Entry{
+			Kind:  "account_opening",
+			Lines: []ledger.Line{ledger.DebitLine(cash, balance), ledger.CreditLine(customer, balance)},
+		}); err != nil {
+			t.Fatalf("funding account: %v", err)
+		}
+	}
+	if err := tx.First(&account, account.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit().Error; err != nil {
+		t.Fatal(err)
+	}
+	return account
+}
+
+// feeTransactions returns the fees logged on account, oldest first.
+func feeTransactions(t *testing.T, db *gorm.DB, account models.Account) []models.Transaction {
+	t.Helper()
+	var transactions []models.Transaction
+	if err := db.Where("account_id = ? AND transaction_type = ?", account.ID, "fee").Order("id").Find(&transactions).Error; err != nil {
+		t.Fatal(err)
+	}
+	return transactions
+}
+
+// Withdrawals up to the free count are free; every one after is charged.
+func TestExcessWithdrawalFreeCount(t *testing.T) {
+	db := openTestDB(t)
+	product := testProduct(t, db, models.FeeRule{Kind: catalog.FeeExcessWithdrawal, Amount: 2_00, FreeCount: 2})
+	account := testAccount(t, db, product, 0)
+
+	now := time.Now()
+	withdraw := func(at time.Time) {
+		t.Helper()
+		if err := db.Create(&models.Transaction{
+			TransactionType: "withdrawal",
+			Amount:          1_00,
+			Currency:        account.Currency,
+			AccountID:       account.ID,
+			Status:          "success",
+			TransactionDate: at,
+		}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Last month's withdrawals do not count towards this month
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	withdraw(monthStart.Add(-time.Minute))
+	withdraw(monthStart.Add(-time.Minute))
+
+	for made := 0; made <= 3; made++ {
+		charges, err := ForWithdrawal(db, &account, now)
+		if err != nil {
+			t.Fatalf("after %d withdrawals: %v", made, err)
+		}
+		wantCharged := made >= 2
+		if wantCharged != (len(charges) == 1) {
+			t.Errorf("after %d withdrawals this month: charges %v, want charged %v", made, charges, wantCharged)
+		}
+		if len(charges) == 1 && (charges[0].Amount != 2_00 || charges[0].Waived) {
+			t.Errorf("after %d withdrawals: charge %+v, want 2.00", made, charges[0])
+		}
+		withdraw(now)
+	}
+}
+
+func TestWaiverMatching(t *testing.T) {
+	db := openTestDB(t)
+	product := testProduct(t, db, models.FeeRule{Kind: catalog.FeeTransfer, Amount: 1_00})
+	other := testProduct(t, db)
+	account := testAccount(t, db, product, 0)
+
+	otherUser := account.UserID + 1
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+
+	cases := []struct {
+		name   string
+		waiver *models.FeeWaiver
+		waived bool
+	}{
+		{"no waiver", nil, false},
+		{"owner", &models.FeeWaiver{UserID: &account.UserID}, true},
+		{"another user", &models.FeeWaiver{UserID: &otherUser}, false},
+		{"product", &models.FeeWaiver{ProductCode: product.Code}, true},
+		{"another product", &models.FeeWaiver{ProductCode: other.Code}, false},
+		{"owner, same kind", &models.FeeWaiver{UserID: &account.UserID, Kind: catalog.FeeTransfer}, true},
+		{"owner, other kind", &models.FeeWaiver{UserID: &account.UserID, Kind: catalog.FeeMonthlyMaintenance}, false},
+		{"product, other kind", &models.FeeWaiver{ProductCode: product.Code, Kind: catalog.FeeExcessWithdrawal}, false},
+		{"not yet expired", &models.FeeWaiver{UserID: &account.UserID, ExpiresAt: &future}, true},
+		{"expired", &models.FeeWaiver{UserID: &account.UserID, ExpiresAt: &past}, false},
+		{"expired product", &models.FeeWaiver{ProductCode: product.Code, ExpiresAt: &past}, false},
+	}
+	for _, tc := range cases {
+		if tc.waiver != nil {
+			tc.waiver.Reason = "fee test"
+			if err := db.Create(tc.waiver).Error; err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		charges, err := ForTransfer(db, &account, time.Now())
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if len(charges) != 1 {
+			t.Fatalf("%s: charges %v, want one", tc.name, charges)
+		}
+		if charges[0].Waived != tc.waived {
+			t.Errorf("%s: waived %v, want %v", tc.name, charges[0].Waived, tc.waived)
+		}
+		if tc.waived && (charges[0].WaiverID == nil || *charges[0].WaiverID != tc.waiver.ID) {
+			t.Errorf("%s: waiver %v, want %d", tc.name, charges[0].WaiverID, tc.waiver.ID)
+		}
+
+		if tc.waiver != nil {
+			if err := db.Unscoped().Delete(tc.waiver).Error; err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+// A monthly fee larger than the balance takes only what is there.
+func TestMonthlyFeeCappedAtBalance(t *testing.T) {
+	db := openTestDB(t)
+	product := testProduct(t, db, models.FeeRule{Kind: catalog.FeeMonthlyMaintenance, Amount: 10_00})
+	account := testAccount(t, db, product, 3_00)
+	month := time.Date(2025, 3, 1, 0, 0, 0, 0, time.Local)
+
+	for i := 0; i < 2; i++ {
+		tx := db.Begin()
+		if err := chargeMonth(tx, &account, month); err != nil {
+			tx.Rollback()
+			t.Fatalf("charging month: %v", err)
+		}
+		if err := tx.Commit().Error; err != nil {
+			t.Fatal(err)
+		}
+		month = month.AddDate(0, 1, 0)
+	}
+
+	// Nothing is left to take the second time
+	fees := feeTransactions(t, db, account)
+	if len(fees) != 1 || fees[0].Amount != 3_00 || fees[0].Status != StatusCharged {
+		t.Fatalf("fees = %+v, want one charge of 3.00", fees)
+	}
+	if err := db.First(&account, account.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if !account.Balance.IsZero() {
+		t.Errorf("balance = %s, want 0.00", account.Balance)
+	}
+}
+
+// An engine that has not run for months charges each missed month once.
+func TestEngineCatchesUpMissedMonths(t *testing.T) {
+	db := openTestDB(t)
+	product := testProduct(t, db, models.FeeRule{Kind: catalog.FeeMonthlyMaintenance, Amount: 5_00})
+	account := testAccount(t, db, product, 100_00)
+	engine := NewEngine(db)
+
+	now := time.Now()
+	thisMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local)
+
+	// The month an account is first seen in is free
+	if err := engine.processAccount(account.ID, now); err != nil {
+		t.Fatalf("first run: %v", err)
+	}
+	if fees := feeTransactions(t, db, account); len(fees) != 0 {
+		t.Fatalf("first run charged %+v", fees)
+	}
+
+	through := monthEnd(thisMonth.AddDate(0, -4, 0))
+	if err := db.Model(&account).Update("fees_charged_through", &through).Error; err != nil {
+		t.Fatal(err)
+	}
+	for run := 0; run < 2; run++ {
+		if err := engine.processAccount(account.ID, now); err != nil {
+			t.Fatalf("catching up: %v", err)
+		}
+	}
+
+	if fees := feeTransactions(t, db, account); len(fees) != 3 {
+		t.Errorf("charged %d months, want 3", len(fees))
+	}
+	if err := db.First(&account, account.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if account.Balance != 85_00 {
+		t.Errorf("balance = %s, want 85.00", account.Balance)
+	}
+	want := monthEnd(thisMonth.AddDate(0, -1, 0)).Format(time.DateOnly)
+	if account.FeesChargedThrough == nil || account.FeesChargedThrough.Format(time.DateOnly) != want {
+		t.Errorf("charged through %v, want %s", account.FeesChargedThrough, want)
+	}
+}
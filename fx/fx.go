@@ -0,0 +1,112 @@
+package fx
+
+import (
+	"bank-app/money"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// RateDecimals is the precision rates are recorded with.
+const RateDecimals = 10
+
+var (
+	ErrNoRate      = errors.New("fx: no exchange rate for currency pair")
+	ErrInvalidRate = errors.New("fx: exchange rate must be a positive decimal")
+)
+
+// Rate is the mid-market price of one unit of From in To.
+type Rate struct {
+	From  money.Currency
+	To    money.Currency
+	Value *big.Rat
+	AsOf  time.Time
+}
+
+// String formats the rate as a decimal with RateDecimals places.
+func (r Rate) String() string {
+	return r.Value.FloatString(RateDecimals)
+}
+
+// FXRateProvider quotes exchange rates between currencies.
+type FXRateProvider interface {
+	Rate(from, to money.Currency) (Rate, error)
+}
+
+// ParseRate parses a positive decimal rate such as "0.9235".
+func ParseRate(s string) (*big.Rat, error) {
+	value, ok := new(big.Rat).SetString(s)
+	if !ok || value.Sign() <= 0 {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidRate, s)
+	}
+	return value, nil
+}
+
+// Conversion is the result of converting an amount at a rate less a spread.
+// Mid is what the amount is worth at the mid-market rate; the customer
+// receives Converted and the bank keeps Spread, so Mid = Converted + Spread.
+type Conversion struct {
+	Rate      Rate
+	SpreadBps int
+	From      money.Money
+	Mid       money.Amount
+	Converted money.Amount
+	Spread    money.Amount
+}
+
+// Convert converts amount at rate, charging spreadBps basis points of the
+// converted value. Results are rounded half to even to the minor unit.
+func Convert(amount money.Amount, rate Rate, spreadBps int) (Conversion, error) {
+	if spreadBps < 0 || spreadBps >= 10000 {
+		return Conversion{}, fmt.Errorf("fx: invalid spread %d bps", spreadBps)
+	}
+
+	// Every supported currency has the same scale, so minor units convert
+	// directly
+	midRat := new(big.Rat).Mul(new(big.Rat).SetInt64(amount.Minor()), rate.Value)
+	mid, err := roundToAmount(midRat)
+	if err != nil {
+		return Conversion{}, err
+	}
+
+	spreadRat := new(big.Rat).Mul(new(big.Rat).SetInt64(mid.Minor()), big.NewRat(int64(spreadBps), 10000))
+	spread, err := roundToAmount(spreadRat)
+	if err != nil {
+		return Conversion{}, err
+	}
+	converted, err := mid.Sub(spread)
+	if err != nil {
+		return Conversion{}, err
+	}
+
+	return Conversion{
+		Rate:      rate,
+		SpreadBps: spreadBps,
+		From:      money.New(amount, rate.From),
+		Mid:       mid,
+		Converted: converted,
+		Spread:    spread,
+	}, nil
+}
+
+// roundToAmount rounds a number of minor units half to even.
+func roundToAmount(r *big.Rat) (money.Amount, error) {
+	quotient, remainder := new(big.Int).QuoRem(r.Num(), r.Denom(), new(big.Int))
+
+	// Compare twice the remainder with the denominator to decide rounding
+	twice := new(big.Int).Mul(new(big.Int).Abs(remainder), big.NewInt(2))
+	switch cmp := twice.Cmp(r.Denom()); {
+	case cmp > 0, cmp == 0 && quotient.Bit(0) == 1:
+		if r.Sign() < 0 {
+			quotient.Sub(quotient, big.NewInt(1))
+		} else {
+			quotient.Add(quotient, big.NewInt(1))
+		}
+	}
+
+	if !quotient.IsInt64() {
+		return 0, money.ErrOverflow
+	}
+	return money.FromMinor(quotient.Int64()), nil
+}
@@ -0,0 +1,144 @@
+package fx
+
+import (
+	"bank-app/money"
+	"errors"
+	"math"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func rate(from, to money.Currency, value string) Rate {
+	r, ok := new(big.Rat).SetString(value)
+	if !ok {
+		panic("bad test rate " + value)
+	}
+	return Rate{From: from, To: to, Value: r}
+}
+
+func TestConvert(t *testing.T) {
+	cases := []struct {
+		name      string
+		amount    money.Amount
+		rate      string
+		spreadBps int
+		mid       money.Amount
+		spread    money.Amount
+	}{
+		{"typical", money.FromMinor(100_00), "0.9235", 50, 92_35, 46},
+		{"no spread", money.FromMinor(100_00), "0.9235", 0, 92_35, 0},
+		{"mid half rounds to even, down", money.FromMinor(1), "0.5", 0, 0, 0},
+		{"mid half rounds to even, up", money.FromMinor(3), "0.5", 0, 2, 0},
+		{"mid half rounds to even, down from odd", money.FromMinor(5), "0.5", 0, 2, 0},
+		{"mid above half rounds up", money.FromMinor(1), "0.51", 0, 1, 0},
+		{"spread half rounds to even, down", money.FromMinor(100), "1", 50, 100, 0},
+		{"spread half rounds to even, up", money.FromMinor(300), "1", 50, 300, 2},
+		{"large rate, spread half rounds to even", money.FromMinor(1_00), "302.5", 100, 302_50, 3_02},
+		{"highest spread", money.FromMinor(100_00), "1", 9999, 100_00, 99_99},
+	}
+	for _, tc := range cases {
+		got, err := Convert(tc.amount, rate(money.USD, money.EUR, tc.rate), tc.spreadBps)
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if got.Mid != tc.mid || got.Spread != tc.spread {
+			t.Errorf("%s: mid %s, spread %s; want %s, %s", tc.name, got.Mid, got.Spread, tc.mid, tc.spread)
+		}
+		if sum, _ := got.Converted.Add(got.Spread); sum != got.Mid {
+			t.Errorf("%s: converted %s + spread %s != mid %s", tc.name, got.Converted, got.Spread, got.Mid)
+		}
+		if got.From != money.New(tc.amount, money.USD) || got.SpreadBps != tc.spreadBps {
+			t.Errorf("%s: from %v at %d bps, want %s USD at %d", tc.name, got.From, got.SpreadBps, tc.amount, tc.spreadBps)
+		}
+	}
+}
+
+func TestConvertRejects(t *testing.T) {
+	usdEur := rate(money.USD, money.EUR, "0.9")
+	for _, bps := range []int{-1, 10000, 20000} {
+		if _, err := Convert(money.FromMinor(100), usdEur, bps); err == nil {
+			t.Errorf("spread of %d bps accepted", bps)
+		}
+	}
+
+	if _, err := Convert(money.FromMinor(math.MaxInt64), rate(money.USD, money.LKR, "300"), 0); !errors.Is(err, money.ErrOverflow) {
+		t.Errorf("overflowing conversion: got %v, want ErrOverflow", err)
+	}
+}
+
+func TestStaticProviderRate(t *testing.T) {
+	provider := NewStaticProvider(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	provider.Set(money.USD, money.EUR, big.NewRat(4, 5))
+
+	cases := []struct {
+		from, to money.Currency
+		want     string
+	}{
+		{money.USD, money.EUR, "0.8000000000"},
+		// Missing pairs come from the inverse
+		{money.EUR, money.USD, "1.2500000000"},
+		{money.GBP, money.GBP, "1.0000000000"},
+	}
+	for _, tc := range cases {
+		got, err := provider.Rate(tc.from, tc.to)
+		if err != nil {
+			t.Errorf("%s/%s: %v", tc.from, tc.to, err)
+			continue
+		}
+		if got.Value.FloatString(10) != tc.want || got.From != tc.from || got.To != tc.to {
+			t.Errorf("%s/%s = %s %s/%s, want %s", tc.from, tc.to, got.Value.FloatString(10), got.From, got.To, tc.want)
+		}
+	}
+
+	if _, err := provider.Rate(money.USD, money.GBP); !errors.Is(err, ErrNoRate) {
+		t.Errorf("unknown pair: got %v, want ErrNoRate", err)
+	}
+
+	// An explicit rate wins over the inverse of the other direction
+	provider.Set(money.EUR, money.USD, big.NewRat(6, 5))
+	if got, _ := provider.Rate(money.EUR, money.USD); got.Value.Cmp(big.NewRat(6, 5)) != 0 {
+		t.Errorf("EUR/USD = %s, want the explicit 1.2", got.Value.FloatString(4))
+	}
+}
+
+func TestLoadCSV(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	provider, err := LoadCSV(write("good.csv", "# from,to,rate\nUSD,EUR,0.9235\n\n usd , gbp , 0.79\n"))
+	if err != nil {
+		t.Fatalf("loading good file: %v", err)
+	}
+	if got, err := provider.Rate(money.USD, money.GBP); err != nil || got.String() != "0.7900000000" {
+		t.Errorf("USD/GBP = %v, %v; want 0.79", got, err)
+	}
+
+	bad := map[string]string{
+		"zero rate":        "USD,EUR,0\n",
+		"negative rate":    "USD,EUR,-0.9\n",
+		"not a number":     "USD,EUR,abc\n",
+		"empty rate":       "USD,EUR,\n",
+		"unknown currency": "USD,XYZ,0.9\n",
+		"missing field":    "USD,EUR\n",
+		"extra field":      "USD,EUR,0.9,1\n",
+	}
+	for name, content := range bad {
+		if _, err := LoadCSV(write("bad.csv", content)); err == nil {
+			t.Errorf("%s: file accepted", name)
+		}
+	}
+
+	if _, err := LoadCSV(filepath.Join(dir, "missing.csv")); err == nil {
+		t.Error("missing file accepted")
+	}
+}
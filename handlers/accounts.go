@@ -1,15 +1,24 @@
 package handlers
 
 import (
+	"bank-app/authz"
 	"bank-app/config"
+	"bank-app/events"
+	"bank-app/fees"
+	"bank-app/fx"
+	"bank-app/ledger"
+	"bank-app/lifecycle"
 	"bank-app/models"
-	"bank-app/rabbitmq"
+	"bank-app/money"
+	"bank-app/outbox"
 	"fmt"
 	"math/rand"
 	"net/http"
+	"sort"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
 )
 
 func GenerateUniqueAccountNumber() string {
@@ -30,17 +39,11 @@ func CreateAccount(c *gin.Context) {
 		return
 	}
 
-	if accountRequest.InitialBalance < 0 {
+	if accountRequest.InitialBalance.IsNegative() {
 		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Initial balance cannot be negative"})
 		return
 	}
 
-	validTypes := map[string]bool{"savings": true, "checking": true}
-	if !validTypes[accountRequest.AccountType] {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Invalid account type"})
-		return
-	}
-
 	userID := c.MustGet("userID").(uint)
 
 	var user models.User
@@ -49,21 +52,111 @@ func CreateAccount(c *gin.Context) {
 		return
 	}
 
-	// Check if this account type already exists for the user
-	var existing models.Account
-	if err := config.DB.Where("user_id = ? AND account_type = ?", userID, accountRequest.AccountType).First(&existing).Error; err == nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Account type already exists"})
+	// The product stays locked until the account exists, so an admin cannot
+	// change its currency underneath it and concurrent requests cannot both
+	// slip under the account limit
+	tx := config.DB.Begin()
+	var product models.AccountProduct
+	if err := tx.Set("gorm:query_option", "FOR UPDATE").Where("code = ?", accountRequest.AccountType).First(&product).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Invalid account type"})
+		return
+	}
+
+	// Products with a fixed currency only open in it
+	currency := money.DefaultCurrency
+	if product.Currency != "" {
+		currency = product.Currency
+	}
+	if accountRequest.Currency != "" {
+		parsed, err := money.ParseCurrency(accountRequest.Currency)
+		if err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Invalid currency"})
+			return
+		}
+		if product.Currency != "" && parsed != product.Currency {
+			tx.Rollback()
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: product.Name + " accounts are only available in " + string(product.Currency)})
+			return
+		}
+		currency = parsed
+	}
+
+	if accountRequest.InitialBalance < product.MinOpeningBalance {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Message: "Initial balance must be at least " + product.MinOpeningBalance.String(),
+		})
 		return
 	}
 
+	// Closed accounts do not count towards the product's limit
+	if product.MaxAccountsPerUser > 0 {
+		var open int
+		if err := tx.Model(&models.Account{}).
+			Where("user_id = ? AND account_type = ? AND status <> ?", userID, product.Code, lifecycle.Closed).
+			Count(&open).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to create account"})
+			return
+		}
+		if open >= product.MaxAccountsPerUser {
+			tx.Rollback()
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Message: fmt.Sprintf("You already have the maximum of %d %s accounts", product.MaxAccountsPerUser, product.Name),
+			})
+			return
+		}
+	}
+
 	account := models.Account{
 		UserID:      userID,
-		AccountType: accountRequest.AccountType,
-		Balance:     accountRequest.InitialBalance,
+		AccountType: product.Code,
+		Currency:    currency,
 		AccountNo:   GenerateUniqueAccountNumber(),
+		Status:      lifecycle.Active,
 	}
 
-	if err := config.DB.Create(&account).Error; err != nil {
+	if err := tx.Create(&account).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to create account"})
+		return
+	}
+
+	customerLedger, err := ledger.CustomerAccount(tx, &account)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to create account"})
+		return
+	}
+
+	// Fund the initial balance from cash so it shows up in the books
+	if accountRequest.InitialBalance.IsPositive() {
+		cash, err := ledger.SystemAccount(tx, ledger.Cash, account.Currency)
+		if err == nil {
+			_, err = ledger.Post(tx, ledger.Entry{
+				Kind:        "account_opening",
+				Description: "Initial deposit for " + account.AccountNo,
+				Lines: []ledger.Line{
+					ledger.DebitLine(cash, accountRequest.InitialBalance),
+					ledger.CreditLine(customerLedger, accountRequest.InitialBalance),
+				},
+			})
+		}
+		if err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to fund account"})
+			return
+		}
+	}
+
+	if err := tx.First(&account, account.ID).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to create account"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to create account"})
 		return
 	}
@@ -73,6 +166,7 @@ func CreateAccount(c *gin.Context) {
 		AccountID:   account.ID,
 		AccountNo:   account.AccountNo,
 		Balance:     account.Balance,
+		Currency:    account.Currency,
 		AccountType: account.AccountType,
 	})
 }
@@ -92,31 +186,61 @@ func CreateAccount(c *gin.Context) {
 // @Router       /accounts/{account_no}/deposit [post]
 func Deposit(c *gin.Context) {
 	accountNo := c.Param("account_no")
-	userID := c.MustGet("userID").(uint)
 	var request models.TransactionRequest
 
-	if err := c.ShouldBindJSON(&request); err != nil {
+	if err := c.ShouldBindJSON(&request); err != nil || !request.Amount.IsPositive() {
 		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Invalid amount"})
 		return
 	}
 
-	// Retrieve the user to get phone number (or email)
+	// Find the account
+	var account models.Account
+	if err := config.DB.Where("account_no = ?", accountNo).First(&account).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Message: "Account not found"})
+		return
+	}
+
+	// Owners deposit into their own accounts; tellers into anyone's
+	if !authz.CanDeposit(principal(c), account) {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{Message: "Access denied"})
+		return
+	}
+	if !amountInAccountCurrency(c, request, account) {
+		return
+	}
+
+	// Retrieve the account owner to get their email
 	var user models.User
-	if err := config.DB.First(&user, userID).Error; err != nil {
+	if err := config.DB.First(&user, account.UserID).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to fetch user info"})
 		return
 	}
 
-	// Find the account
-	var account models.Account
-	if err := config.DB.Where("account_no = ?", accountNo).First(&account).Error; err != nil {
-		c.JSON(http.StatusNotFound, models.ErrorResponse{Message: "Account not found"})
+	tx := config.DB.Begin()
+	if err := lockAccounts(tx, &account); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to lock account"})
+		return
+	}
+	if !accountPermits(c, account, lifecycle.Deposit) {
+		tx.Rollback()
 		return
 	}
 
-	// Add the deposit amount to the balance
-	account.Balance += request.Amount
-	if err := config.DB.Save(&account).Error; err != nil {
+	// Guard against overflowing the balance
+	if _, err := account.Balance.Add(request.Amount); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Amount too large"})
+		return
+	}
+
+	// Post cash in against the customer's account
+	journal, err := postMovement(tx, "deposit", "Deposit to "+account.AccountNo, request.Amount,
+		func() (*models.LedgerAccount, error) { return ledger.SystemAccount(tx, ledger.Cash, account.Currency) },
+		func() (*models.LedgerAccount, error) { return ledger.CustomerAccount(tx, &account) },
+	)
+	if err != nil {
+		tx.Rollback()
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to update balance"})
 		return
 	}
@@ -125,28 +249,48 @@ func Deposit(c *gin.Context) {
 	transaction := models.Transaction{
 		TransactionType: "deposit",
 		Amount:          request.Amount,
+		Currency:        account.Currency,
 		AccountID:       account.ID,
 		Status:          "success",
+		JournalEntryID:  &journal.ID,
 		TransactionDate: time.Now(),
 	}
-	if err := config.DB.Create(&transaction).Error; err != nil {
+	if err := tx.Create(&transaction).Error; err != nil {
+		tx.Rollback()
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to log transaction"})
 		return
 	}
 
-	_ = rabbitmq.Publish(map[string]interface{}{
-		"type":      "deposit",
-		"status":    "success",
-		"user_id":   account.UserID,
-		"amount":    request.Amount,
-		"accountNo": account.AccountNo,
-		"timestamp": time.Now().UTC(),
-		"to_email":  user.Email,
-	})
+	if err := tx.First(&account, account.ID).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to update balance"})
+		return
+	}
+
+	// Queue the notification so it is published only if the deposit commits
+	if err := enqueueEvent(c, tx, &events.DepositSucceeded{
+		TransactionID: transaction.ID,
+		UserID:        account.UserID,
+		AccountNo:     account.AccountNo,
+		Amount:        request.Amount,
+		Currency:      account.Currency,
+		Balance:       account.Balance,
+		ToEmail:       user.Email,
+	}); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to queue notification"})
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to update balance"})
+		return
+	}
 
 	c.JSON(http.StatusOK, models.TransactionResponse{
-		Message: "Deposit successful",
-		Balance: account.Balance,
+		Message:  "Deposit successful",
+		Balance:  account.Balance,
+		Currency: account.Currency,
 	})
 }
 
@@ -159,6 +303,7 @@ func Deposit(c *gin.Context) {
 // @Param        request     body      models.TransactionRequest  true  "Withdrawal amount"
 // @Success      200         {object}  models.TransactionResponse
 // @Failure      400         {object}  models.ErrorResponse
+// @Failure      401         {object}  models.StepUpChallengeResponse
 // @Failure      403         {object}  models.ErrorResponse
 // @Failure      500         {object}  models.ErrorResponse
 // @Security     BearerAuth
@@ -167,36 +312,62 @@ func Withdraw(c *gin.Context) {
 	accountNo := c.Param("account_no")
 	var request models.TransactionRequest
 
-	if err := c.ShouldBindJSON(&request); err != nil || request.Amount <= 0 {
+	if err := c.ShouldBindJSON(&request); err != nil || !request.Amount.IsPositive() {
 		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Invalid or missing amount"})
 		return
 	}
 
-	// Get authenticated user's ID from context
-	userID := c.MustGet("userID").(uint)
+	// Find the account AND ensure the authenticated user may withdraw from it
+	var account models.Account
+	if err := config.DB.Where("account_no = ?", accountNo).First(&account).Error; err != nil || !authz.CanWithdraw(principal(c), account) {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{Message: "Account not found or access denied"})
+		return
+	}
+	if !amountInAccountCurrency(c, request, account) {
+		return
+	}
 
 	var user models.User
-	if err := config.DB.First(&user, userID).Error; err != nil {
+	if err := config.DB.First(&user, account.UserID).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to fetch user info"})
 		return
 	}
 
-	// Find the account AND ensure it belongs to the authenticated user
-	var account models.Account
-	if err := config.DB.Where("account_no = ? AND user_id = ?", accountNo, userID).First(&account).Error; err != nil {
-		c.JSON(http.StatusForbidden, models.ErrorResponse{Message: "Account not found or access denied"})
+	tx := config.DB.Begin()
+	if err := lockAccounts(tx, &account); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to lock account"})
+		return
+	}
+	if !accountPermits(c, account, lifecycle.Withdraw) {
+		tx.Rollback()
 		return
 	}
 
-	// Check if the account has enough balance
-	if account.Balance < request.Amount {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Insufficient balance"})
+	// Check if the account has enough balance for the withdrawal and its fees
+	charges, err := fees.ForWithdrawal(tx, &account, time.Now())
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to assess fees"})
+		return
+	}
+	debit, ok := coversAmountAndFees(c, account, request.Amount, charges)
+	if !ok {
+		tx.Rollback()
+		return
+	}
+	if !requireStepUp(c, debit, account.Currency) {
+		tx.Rollback()
 		return
 	}
 
-	// Deduct the amount
-	account.Balance -= request.Amount
-	if err := config.DB.Save(&account).Error; err != nil {
+	// Post the customer's account out against cash
+	journal, err := postMovement(tx, "withdrawal", "Withdrawal from "+account.AccountNo, request.Amount,
+		func() (*models.LedgerAccount, error) { return ledger.CustomerAccount(tx, &account) },
+		func() (*models.LedgerAccount, error) { return ledger.SystemAccount(tx, ledger.Cash, account.Currency) },
+	)
+	if err != nil {
+		tx.Rollback()
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to update balance"})
 		return
 	}
@@ -205,29 +376,55 @@ func Withdraw(c *gin.Context) {
 	transaction := models.Transaction{
 		TransactionType: "withdrawal",
 		Amount:          request.Amount,
+		Currency:        account.Currency,
 		AccountID:       account.ID,
 		Status:          "success",
+		JournalEntryID:  &journal.ID,
 		TransactionDate: time.Now(),
 	}
 
-	if err := config.DB.Create(&transaction).Error; err != nil {
+	if err := tx.Create(&transaction).Error; err != nil {
+		tx.Rollback()
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to log transaction"})
 		return
 	}
+	if err := fees.Post(tx, &account, charges, &transaction); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to charge fees"})
+		return
+	}
 
-	_ = rabbitmq.Publish(map[string]interface{}{
-		"type":      "withdraw",
-		"status":    "success",
-		"user_id":   account.UserID,
-		"amount":    request.Amount,
-		"accountNo": account.AccountNo,
-		"timestamp": time.Now().UTC(),
-		"to_email":  user.Email,
-	})
+	if err := tx.First(&account, account.ID).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to update balance"})
+		return
+	}
+
+	// Queue the notification so it is published only if the withdrawal commits
+	if err := enqueueEvent(c, tx, &events.WithdrawalSucceeded{
+		TransactionID: transaction.ID,
+		UserID:        account.UserID,
+		AccountNo:     account.AccountNo,
+		Amount:        request.Amount,
+		Currency:      account.Currency,
+		Balance:       account.Balance,
+		ToEmail:       user.Email,
+	}); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to queue notification"})
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to update balance"})
+		return
+	}
 
 	c.JSON(http.StatusOK, models.TransactionResponse{
-		Message: "Withdrawal successful",
-		Balance: account.Balance,
+		Message:  "Withdrawal successful",
+		Balance:  account.Balance,
+		Currency: account.Currency,
+		Fees:     charges,
 	})
 }
 
@@ -237,23 +434,24 @@ func Transfer(c *gin.Context) {
 
 	var request models.TransactionRequest
 
-	if err := c.ShouldBindJSON(&request); err != nil || request.Amount <= 0 {
+	if err := c.ShouldBindJSON(&request); err != nil || !request.Amount.IsPositive() {
 		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Invalid or missing amount"})
 		return
 	}
 
-	userID := c.MustGet("userID").(uint)
-
-	var sender models.User
-	if err := config.DB.First(&sender, userID).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to fetch user info"})
+	// Ensure the logged-in user may send from the 'from' account
+	var fromAccount models.Account
+	if err := config.DB.Where("account_no = ?", fromAccountNo).First(&fromAccount).Error; err != nil || !authz.CanTransferFrom(principal(c), fromAccount) {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{Message: "You do not have access to this account"})
+		return
+	}
+	if !amountInAccountCurrency(c, request, fromAccount) {
 		return
 	}
 
-	// Ensure the 'from' account belongs to the logged-in user
-	var fromAccount models.Account
-	if err := config.DB.Where("account_no = ? AND user_id = ?", fromAccountNo, userID).First(&fromAccount).Error; err != nil {
-		c.JSON(http.StatusForbidden, models.ErrorResponse{Message: "You do not have access to this account"})
+	var sender models.User
+	if err := config.DB.First(&sender, fromAccount.UserID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to fetch user info"})
 		return
 	}
 
@@ -270,37 +468,80 @@ func Transfer(c *gin.Context) {
 		return
 	}
 
-	// Check sufficient balance
-	if fromAccount.Balance < request.Amount {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Insufficient balance"})
+	conversion, credited, ok := convertForTransfer(c, request.Amount, fromAccount, toAccount)
+	if !ok {
 		return
 	}
 
-	// Execute transfer
-	fromAccount.Balance -= request.Amount
-	toAccount.Balance += request.Amount
-
 	// Use transaction to ensure atomicity
 	tx := config.DB.Begin()
-	if err := tx.Save(&fromAccount).Error; err != nil {
+	if err := lockAccounts(tx, &fromAccount, &toAccount); err != nil {
 		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to update sender account"})
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to lock accounts"})
 		return
 	}
-	if err := tx.Save(&toAccount).Error; err != nil {
+	if !accountPermits(c, fromAccount, lifecycle.TransferOut) || !accountPermits(c, toAccount, lifecycle.TransferIn) {
 		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to update receiver account"})
 		return
 	}
 
+	// Check sufficient balance for the transfer and its fees
+	charges, err := fees.ForTransfer(tx, &fromAccount, time.Now())
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to assess fees"})
+		return
+	}
+	debit, ok := coversAmountAndFees(c, fromAccount, request.Amount, charges)
+	if !ok {
+		tx.Rollback()
+		return
+	}
+	if !requireStepUp(c, debit, fromAccount.Currency) {
+		tx.Rollback()
+		return
+	}
+
+	// Guard against overflowing the receiver's balance
+	if _, err := toAccount.Balance.Add(credited); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Amount too large"})
+		return
+	}
+
+	// Execute transfer as a single balanced journal entry
+	description := "Transfer from " + fromAccount.AccountNo + " to " + toAccount.AccountNo
+	var journal *models.JournalEntry
+	var fxRecord *models.FXConversion
+	if conversion == nil {
+		journal, err = postMovement(tx, "transfer", description, request.Amount,
+			func() (*models.LedgerAccount, error) { return ledger.CustomerAccount(tx, &fromAccount) },
+			func() (*models.LedgerAccount, error) { return ledger.CustomerAccount(tx, &toAccount) },
+		)
+	} else {
+		journal, fxRecord, err = postConversion(tx, description, &fromAccount, &toAccount, *conversion)
+	}
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to post transfer"})
+		return
+	}
+	var fxConversionID *uint
+	if fxRecord != nil {
+		fxConversionID = &fxRecord.ID
+	}
+
 	// Log sender transaction
 	transactionFrom := models.Transaction{
 		TransactionType: "transfer",
 		Amount:          request.Amount,
+		Currency:        fromAccount.Currency,
 		AccountID:       fromAccount.ID,
 		FromAccountID:   &fromAccount.ID,
 		ToAccountID:     &toAccount.ID,
 		Status:          "success",
+		JournalEntryID:  &journal.ID,
+		FXConversionID:  fxConversionID,
 		TransactionDate: time.Now(),
 	}
 	if err := tx.Create(&transactionFrom).Error; err != nil {
@@ -308,15 +549,23 @@ func Transfer(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to log sender transaction"})
 		return
 	}
+	if err := fees.Post(tx, &fromAccount, charges, &transactionFrom); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to charge fees"})
+		return
+	}
 
 	// Log receiver transaction
 	transactionTo := models.Transaction{
 		TransactionType: "transfer",
-		Amount:          request.Amount,
+		Amount:          credited,
+		Currency:        toAccount.Currency,
 		AccountID:       toAccount.ID,
 		FromAccountID:   &fromAccount.ID,
 		ToAccountID:     &toAccount.ID,
 		Status:          "success",
+		JournalEntryID:  &journal.ID,
+		FXConversionID:  fxConversionID,
 		TransactionDate: time.Now(),
 	}
 	if err := tx.Create(&transactionTo).Error; err != nil {
@@ -325,39 +574,221 @@ func Transfer(c *gin.Context) {
 		return
 	}
 
-	// Commit transaction
-	tx.Commit()
+	if err := tx.First(&fromAccount, fromAccount.ID).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to update sender account"})
+		return
+	}
 
 	var receiver models.User
-	if err := config.DB.First(&receiver, toAccount.UserID).Error; err != nil {
+	if err := tx.First(&receiver, toAccount.UserID).Error; err != nil {
+		tx.Rollback()
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to fetch receiver info"})
 		return
 	}
-	_ = rabbitmq.Publish(map[string]interface{}{
-		"type":      "transfer_sent",
-		"status":    "success",
-		"user_id":   fromAccount.UserID,
-		"amount":    request.Amount,
-		"from":      fromAccount.AccountNo,
-		"to":        toAccount.AccountNo,
-		"timestamp": time.Now().UTC(),
-		"to_email":  sender.Email,
-	})
 
-	_ = rabbitmq.Publish(map[string]interface{}{
-		"type":      "transfer_received",
-		"status":    "success",
-		"user_id":   toAccount.UserID,
-		"amount":    request.Amount,
-		"from":      fromAccount.AccountNo,
-		"to":        toAccount.AccountNo,
-		"timestamp": time.Now().UTC(),
-		"to_email":  receiver.Email,
-	})
+	// Queue both notifications so they are published only if the transfer commits
+	notifications := []events.Event{
+		&events.TransferSent{
+			TransactionID: transactionFrom.ID,
+			UserID:        fromAccount.UserID,
+			FromAccountNo: fromAccount.AccountNo,
+			ToAccountNo:   toAccount.AccountNo,
+			Amount:        request.Amount,
+			Currency:      fromAccount.Currency,
+			ToEmail:       sender.Email,
+		},
+		&events.TransferReceived{
+			TransactionID: transactionTo.ID,
+			UserID:        toAccount.UserID,
+			FromAccountNo: fromAccount.AccountNo,
+			ToAccountNo:   toAccount.AccountNo,
+			Amount:        credited,
+			Currency:      toAccount.Currency,
+			ToEmail:       receiver.Email,
+		},
+	}
+	for _, notification := range notifications {
+		if err := enqueueEvent(c, tx, notification); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to queue notification"})
+			return
+		}
+	}
+
+	// Commit transaction
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to post transfer"})
+		return
+	}
 
 	c.JSON(http.StatusOK, models.TransactionResponse{
-		Message: "Transfer successful",
-		Balance: fromAccount.Balance,
+		Message:      "Transfer successful",
+		Balance:      fromAccount.Balance,
+		Currency:     fromAccount.Currency,
+		FXConversion: fxRecord,
+		Fees:         charges,
+	})
+}
+
+// convertForTransfer works out what the receiver of a transfer is credited.
+// Cross-currency transfers are converted at the current rate, less the bank's
+// spread; the conversion is nil when both accounts share a currency.
+func convertForTransfer(c *gin.Context, amount money.Amount, from, to models.Account) (*fx.Conversion, money.Amount, bool) {
+	if from.Currency == to.Currency {
+		return nil, amount, true
+	}
+	rate, err := config.FXRates.Rate(from.Currency, to.Currency)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "No exchange rate available for " +
+			string(from.Currency) + " to " + string(to.Currency)})
+		return nil, 0, false
+	}
+	converted, err := fx.Convert(amount, rate, config.FXSpreadBps())
+	if err != nil || !converted.Converted.IsPositive() {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Amount is too small or too large to convert"})
+		return nil, 0, false
+	}
+	return &converted, converted.Converted, true
+}
+
+// coversAmountAndFees rejects the request if the account's balance cannot pay
+// amount plus the fees that are not waived. Otherwise it returns that total,
+// the whole debit to the account.
+func coversAmountAndFees(c *gin.Context, account models.Account, amount money.Amount, charges []models.FeeCharge) (money.Amount, bool) {
+	feeTotal, err := fees.Total(charges)
+	if err == nil {
+		amount, err = amount.Add(feeTotal)
+	}
+	if err != nil || account.Balance < amount {
+		message := "Insufficient balance"
+		if feeTotal.IsPositive() {
+			message = "Insufficient balance to cover the amount and " + feeTotal.String() + " in fees"
+		}
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: message})
+		return 0, false
+	}
+	return amount, true
+}
+
+// amountInAccountCurrency rejects a request whose stated currency is not the
+// account's. Amounts are never silently reinterpreted in another currency.
+func amountInAccountCurrency(c *gin.Context, request models.TransactionRequest, account models.Account) bool {
+	if request.Currency == "" {
+		return true
+	}
+	currency, err := money.ParseCurrency(request.Currency)
+	if err != nil || currency != account.Currency {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Message: "Amount must be in the account currency " + string(account.Currency),
+		})
+		return false
+	}
+	return true
+}
+
+// postConversion posts a cross-currency transfer. Each currency balances on
+// its own through the FX position account, and the spread is booked as fee
+// income in the receiving currency.
+func postConversion(tx *gorm.DB, description string, from, to *models.Account, conversion fx.Conversion) (*models.JournalEntry, *models.FXConversion, error) {
+	sender, err := ledger.CustomerAccount(tx, from)
+	if err != nil {
+		return nil, nil, err
+	}
+	receiver, err := ledger.CustomerAccount(tx, to)
+	if err != nil {
+		return nil, nil, err
+	}
+	positionFrom, err := ledger.SystemAccount(tx, ledger.FX, from.Currency)
+	if err != nil {
+		return nil, nil, err
+	}
+	positionTo, err := ledger.SystemAccount(tx, ledger.FX, to.Currency)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	lines := []ledger.Line{
+		ledger.DebitLine(sender, conversion.From.Amount),
+		ledger.CreditLine(positionFrom, conversion.From.Amount),
+		ledger.DebitLine(positionTo, conversion.Mid),
+		ledger.CreditLine(receiver, conversion.Converted),
+	}
+	if conversion.Spread.IsPositive() {
+		fees, err := ledger.SystemAccount(tx, ledger.Fees, to.Currency)
+		if err != nil {
+			return nil, nil, err
+		}
+		lines = append(lines, ledger.CreditLine(fees, conversion.Spread))
+	}
+
+	journal, err := ledger.Post(tx, ledger.Entry{Kind: "fx_transfer", Description: description, Lines: lines})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	record := models.FXConversion{
+		JournalEntryID: journal.ID,
+		FromCurrency:   from.Currency,
+		ToCurrency:     to.Currency,
+		FromAmount:     conversion.From.Amount,
+		MidAmount:      conversion.Mid,
+		ToAmount:       conversion.Converted,
+		SpreadAmount:   conversion.Spread,
+		Rate:           conversion.Rate.String(),
+		SpreadBps:      conversion.SpreadBps,
+		RateAsOf:       conversion.Rate.AsOf,
+	}
+	if err := tx.Create(&record).Error; err != nil {
+		return nil, nil, err
+	}
+	return journal, &record, nil
+}
+
+// enqueueEvent wraps event in an envelope carrying the request's correlation
+// ID and writes it to the outbox in tx.
+func enqueueEvent(c *gin.Context, tx *gorm.DB, event events.Event) error {
+	envelope, err := events.New(event, c.GetString("correlationID"))
+	if err != nil {
+		return err
+	}
+	return outbox.Enqueue(tx, envelope)
+}
+
+// lockAccounts re-reads the given accounts with SELECT ... FOR UPDATE so
+// their balances cannot change until tx ends. Rows are locked in ascending ID
+// order, so two transfers between the same accounts cannot deadlock.
+func lockAccounts(tx *gorm.DB, accounts ...*models.Account) error {
+	sorted := append([]*models.Account(nil), accounts...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+
+	for _, account := range sorted {
+		if err := tx.Set("gorm:query_option", "FOR UPDATE").First(account, account.ID).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// postMovement posts a two-line journal entry moving amount from the debit
+// side to the credit side.
+func postMovement(tx *gorm.DB, kind, description string, amount money.Amount,
+	debit, credit func() (*models.LedgerAccount, error)) (*models.JournalEntry, error) {
+	debitAccount, err := debit()
+	if err != nil {
+		return nil, err
+	}
+	creditAccount, err := credit()
+	if err != nil {
+		return nil, err
+	}
+	return ledger.Post(tx, ledger.Entry{
+		Kind:        kind,
+		Description: description,
+		Lines: []ledger.Line{
+			ledger.DebitLine(debitAccount, amount),
+			ledger.CreditLine(creditAccount, amount),
+		},
 	})
 }
 
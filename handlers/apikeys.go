@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"bank-app/authz"
+	"bank-app/config"
+	"bank-app/models"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	defaultAPIKeyDays = 90
+	maxAPIKeyDays     = 365
+)
+
+func apiKeyResponse(key models.APIKey) models.APIKeyResponse {
+	return models.APIKeyResponse{
+		ID:         key.ID,
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Scopes:     key.ScopeList(),
+		ExpiresAt:  key.ExpiresAt,
+		LastUsedAt: key.LastUsedAt,
+		CreatedAt:  key.CreatedAt,
+	}
+}
+
+// @Summary      Create an API key
+// @Description  Creates an API key that acts as the current user, limited to the given scopes ("read", "transfers"). The key is returned only in this response. Keys expire after expires_in_days (default 90, at most 365).
+// @Tags         API keys
+// @Accept       json
+// @Produce      json
+// @Param        request  body      models.CreateAPIKeyRequest  true  "Key name, scopes and lifetime"
+// @Success      201      {object}  models.CreatedAPIKeyResponse
+// @Failure      400      {object}  models.ErrorResponse
+// @Failure      500      {object}  models.ErrorResponse
+// @Security     BearerAuth
+// @Router       /users/me/api-keys [post]
+func CreateAPIKey(c *gin.Context) {
+	var req models.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	seen := map[string]bool{}
+	var scopes []string
+	for _, scope := range req.Scopes {
+		if !authz.ValidScope(scope) {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Invalid scope " + scope})
+			return
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+
+	days := req.ExpiresInDays
+	if days == 0 {
+		days = defaultAPIKeyDays
+	}
+	if days < 0 || days > maxAPIKeyDays {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "expires_in_days must be between 1 and 365"})
+		return
+	}
+	expiresAt := time.Now().AddDate(0, 0, days)
+
+	key, prefix, hash, err := config.GenerateAPIKey()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to generate API key"})
+		return
+	}
+	apiKey := models.APIKey{
+		UserID:    c.MustGet("userID").(uint),
+		Name:      req.Name,
+		Prefix:    prefix,
+		KeyHash:   hash,
+		Scopes:    strings.Join(scopes, ","),
+		ExpiresAt: &expiresAt,
+	}
+	if err := config.DB.Create(&apiKey).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to create API key"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.CreatedAPIKeyResponse{
+		APIKeyResponse: apiKeyResponse(apiKey),
+		Key:            key,
+	})
+}
+
+// @Summary      List API keys
+// @Description  Lists the current user's API keys that have not been revoked. The keys themselves are never shown again.
+// @Tags         API keys
+// @Produce      json
+// @Success      200  {array}   models.APIKeyResponse
+// @Failure      500  {object}  models.ErrorResponse
+// @Security     BearerAuth
+// @Router       /users/me/api-keys [get]
+func GetAPIKeys(c *gin.Context) {
+	var keys []models.APIKey
+	if err := config.DB.Where("user_id = ? AND revoked_at IS NULL", c.MustGet("userID").(uint)).
+		Order("id").Find(&keys).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to retrieve API keys"})
+		return
+	}
+
+	response := make([]models.APIKeyResponse, 0, len(keys))
+	for _, key := range keys {
+		response = append(response, apiKeyResponse(key))
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// @Summary      Revoke an API key
+// @Description  Revokes one of the current user's API keys; it stops working immediately
+// @Tags         API keys
+// @Produce      json
+// @Param        id   path      string  true  "API key ID"
+// @Success      200  {object}  map[string]string
+// @Failure      404  {object}  models.ErrorResponse
+// @Failure      500  {object}  models.ErrorResponse
+// @Security     BearerAuth
+// @Router       /users/me/api-keys/{id} [delete]
+func RevokeAPIKey(c *gin.Context) {
+	var key models.APIKey
+	err := config.DB.Where("id = ? AND user_id = ? AND revoked_at IS NULL", c.Param("id"), c.MustGet("userID").(uint)).
+		First(&key).Error
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{Message: "API key not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to retrieve API key"})
+		}
+		return
+	}
+
+	now := time.Now()
+	if err := config.DB.Model(&key).Update("revoked_at", &now).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to revoke API key"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "API key revoked"})
+}
+
+// revokeAPIKeys revokes every API key the user holds, for when their
+// password may have been known to someone else who could have minted one.
+func revokeAPIKeys(tx *gorm.DB, userID uint) error {
+	now := time.Now()
+	return tx.Model(&models.APIKey{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", &now).Error
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"bank-app/authz"
 	"bank-app/config"
 	"bank-app/models"
 	"net/http"
@@ -19,6 +20,11 @@ func SignUp(c *gin.Context) {
 		return
 	}
 
+	if err := config.LoadPasswordPolicy().Check(req.Password); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: err.Error()})
+		return
+	}
+
 	// Hash the password before saving
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
@@ -33,32 +39,42 @@ func SignUp(c *gin.Context) {
 		LastName:  req.LastName,
 		Email:     req.Email,
 		Password:  string(hashedPassword),
+		Role:      authz.RoleCustomer,
 	}
 
-	// Save user
-	if err := config.DB.Create(&user).Error; err != nil {
+	// Save user and queue the email that verifies their address
+	tx := config.DB.Begin()
+	if err := tx.Create(&user).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Message: "Failed to create user"})
+		return
+	}
+	if err := sendEmailVerification(c, tx, user); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to send verification email"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
 			Message: "Failed to create user"})
 		return
 	}
 
-	// Generate JWT
-	token, err := config.GenerateJWT(user.ID)
+	// Start a session and issue its tokens
+	tokens, err := startSession(user, config.ACRPassword)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Token generation failed"})
 		return
 	}
 
-	userResp := models.UserResponse{
-		ID:        user.ID,
-		FirstName: user.FirstName,
-		LastName:  user.LastName,
-		Email:     user.Email,
-	}
+	userResp := userResponse(user)
 	c.JSON(http.StatusCreated, gin.H{
-		"message": "User registered successfully",
-		"token":   token,
-		"user":    userResp,
+		"message":       "User registered successfully",
+		"token":         tokens.Token,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
+		"user":          userResp,
 	},
 	)
 }
@@ -71,7 +87,17 @@ func Login(c *gin.Context) {
 	}
 
 	var user models.User
-	if err := config.DB.Where("email = ?", req.Email).First(&user).Error; err != nil {
+	var owner *models.User
+	if err := config.DB.Where("email = ?", req.Email).First(&user).Error; err == nil {
+		owner = &user
+	}
+
+	// The attempt is counted before the password is checked, and refused
+	// outright while the account or IP is backing off
+	if !countLoginAttempt(c, req.Email, owner) {
+		return
+	}
+	if owner == nil {
 		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Message: "Invalid credentials"})
 		return
 	}
@@ -82,21 +108,39 @@ func Login(c *gin.Context) {
 		return
 	}
 
-	// Generate JWT
-	token, err := config.GenerateJWT(user.ID)
+	// With two-factor enabled the password only earns a challenge, which
+	// /login/totp exchanges for tokens. Earlier failures still count
+	// towards the code
+	if user.TOTPEnabled {
+		refundLoginAttempt(c, user.Email)
+		challenge, err := config.GenerateMFAChallenge(user.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Token generation failed"})
+			return
+		}
+		c.JSON(http.StatusOK, models.MFAChallengeResponse{
+			Message:        "Two-factor authentication required",
+			MFARequired:    true,
+			ChallengeToken: challenge,
+			ExpiresIn:      int(config.MFAChallengeTTL.Seconds()),
+		})
+		return
+	}
+
+	loginSucceeded(c, user.Email)
+
+	// Start a session and issue its tokens
+	tokens, err := startSession(user, config.ACRPassword)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Token generation failed"})
 		return
 	}
 
 	c.JSON(http.StatusOK, models.LoginResponse{
-		Message: "Login successful",
-		Token:   token,
-		User: models.UserResponse{
-			ID:        user.ID,
-			FirstName: user.FirstName,
-			LastName:  user.LastName,
-			Email:     user.Email,
-		},
+		Message:      "Login successful",
+		Token:        tokens.Token,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
+		User:         userResponse(user),
 	})
 }
@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"bank-app/config"
+	"bank-app/ledger"
+	"bank-app/lifecycle"
+	"bank-app/models"
+	"bank-app/money"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/mysql"
+)
+
+// openTestDB connects to the MySQL database in TEST_MYSQL_DSN and migrates
+// it, or skips the test when none is configured. Row locks are what is under
+// test, so there is no in-memory substitute.
+func openTestDB(t *testing.T) {
+	t.Helper()
+	dsn := os.Getenv("TEST_MYSQL_DSN")
+	if dsn == "" {
+		t.Skip("TEST_MYSQL_DSN is not set, e.g. user:pass@tcp(localhost:3306)/bank_test?charset=utf8mb4&parseTime=True&loc=Local")
+	}
+
+	db, err := gorm.Open("mysql", dsn)
+	if err != nil {
+		t.Fatalf("connecting to test database: %v", err)
+	}
+	db.DB().SetMaxOpenConns(50)
+	config.DB = db
+	t.Cleanup(func() { db.Close() })
+
+	if err := config.Migrate(); err != nil {
+		t.Fatalf("migrating test database: %v", err)
+	}
+}
+
+// fundedAccount opens an active account for user holding balance, funded
+// from cash through the ledger.
+func fundedAccount(t *testing.T, userID uint, balance money.Amount) models.Account {
+	t.Helper()
+	account := models.Account{
+		UserID:      userID,
+		AccountNo:   GenerateUniqueAccountNumber(),
+		Currency:    money.DefaultCurrency,
+		AccountType: "checking",
+		Status:      lifecycle.Active,
+	}
+	tx := config.DB.Begin()
+	if err := tx.Create(&account).Error; err != nil {
+		tx.Rollback()
+		t.Fatalf("creating account: %v", err)
+	}
+	_, err := postMovement(tx, "account_opening", "Test funding for "+account.AccountNo, balance,
+		func() (*models.LedgerAccount, error) { return ledger.SystemAccount(tx, ledger.Cash, account.Currency) },
+		func() (*models.LedgerAccount, error) { return ledger.CustomerAccount(tx, &account) },
+	)
+	if err != nil {
+		tx.Rollback()
+		t.Fatalf("funding account: %v", err)
+	}
+	if err := tx.Commit().Error; err != nil {
+		t.Fatalf("funding account: %v", err)
+	}
+	return account
+}
+
+func TestConcurrentWithdrawalsAndTransfersLoseNoUpdates(t *testing.T) {
+	openTestDB(t)
+	gin.SetMode(gin.TestMode)
+
+	now := time.Now()
+	user := models.User{
+		FirstName:       "Concurrency",
+		LastName:        "Test",
+		Email:           fmt.Sprintf("concurrency-%d@example.com", now.UnixNano()),
+		Role:            "customer",
+		EmailVerifiedAt: &now,
+	}
+	if err := config.DB.Create(&user).Error; err != nil {
+		t.Fatalf("creating user: %v", err)
+	}
+
+	a := fundedAccount(t, user.ID, money.FromMinor(100_00))
+	b := fundedAccount(t, user.ID, money.FromMinor(100_00))
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("userID", user.ID)
+		c.Set("role", "customer")
+		c.Set("correlationID", "concurrency-test")
+	})
+	router.POST("/accounts/:account_no/withdraw", Withdraw)
+	router.POST("/accounts/transfer/:from_account/:to_account", Transfer)
+
+	const rounds = 25
+	requests := []struct{ path, body string }{
+		{"/accounts/" + a.AccountNo + "/withdraw", `{"amount":"1.00"}`},
+		{"/accounts/transfer/" + a.AccountNo + "/" + b.AccountNo, `{"amount":"1.00"}`},
+		{"/accounts/transfer/" + b.AccountNo + "/" + a.AccountNo, `{"amount":"2.00"}`},
+	}
+
+	var wg sync.WaitGroup
+	failures := make(chan string, rounds*len(requests))
+	for i := 0; i < rounds; i++ {
+		for _, r := range requests {
+			wg.Add(1)
+			go func(path, body string) {
+				defer wg.Done()
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+				if w.Code != http.StatusOK {
+					failures <- fmt.Sprintf("%s: %d %s", path, w.Code, w.Body.String())
+				}
+			}(r.path, r.body)
+		}
+	}
+	wg.Wait()
+	close(failures)
+	for failure := range failures {
+		t.Errorf("request failed: %s", failure)
+	}
+
+	// A: 100 - 25 withdrawn - 25 sent + 50 received. B: 100 + 25 - 50.
+	want := map[uint]money.Amount{a.ID: money.FromMinor(100_00), b.ID: money.FromMinor(75_00)}
+	for id, expected := range want {
+		var account models.Account
+		if err := config.DB.First(&account, id).Error; err != nil {
+			t.Fatalf("reloading account: %v", err)
+		}
+		if account.Balance != expected {
+			t.Errorf("account %s balance = %s, want %s", account.AccountNo, account.Balance, expected)
+		}
+
+		ledgerAccount, err := ledger.CustomerAccount(config.DB, &account)
+		if err != nil {
+			t.Fatalf("loading ledger account: %v", err)
+		}
+		derived, err := ledger.Balance(config.DB, ledgerAccount)
+		if err != nil {
+			t.Fatalf("deriving balance: %v", err)
+		}
+		if derived != expected {
+			t.Errorf("account %s ledger balance = %s, want %s", account.AccountNo, derived, expected)
+		}
+	}
+
+	var transactions int
+	if err := config.DB.Model(&models.Transaction{}).
+		Where("account_id IN (?)", []uint{a.ID, b.ID}).
+		Count(&transactions).Error; err != nil {
+		t.Fatalf("counting transactions: %v", err)
+	}
+	// One row per withdrawal and two per transfer
+	if want := rounds + 4*rounds; transactions != want {
+		t.Errorf("logged %d transactions, want %d", transactions, want)
+	}
+}
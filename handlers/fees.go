@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"bank-app/authz"
+	"bank-app/catalog"
+	"bank-app/config"
+	"bank-app/fees"
+	"bank-app/lifecycle"
+	"bank-app/models"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+// @Summary      Quote a transfer
+// @Description  Works out the fees, any currency conversion and the total debit for a transfer without making it
+// @Tags         Transactions
+// @Accept       json
+// @Produce      json
+// @Param        from_account  path      string                     true  "Sender account number"
+// @Param        to_account    path      string                     true  "Receiver account number"
+// @Param        request       body      models.TransactionRequest  true  "Transfer amount"
+// @Success      200           {object}  models.TransferQuoteResponse
+// @Failure      400           {object}  models.ErrorResponse
+// @Failure      403           {object}  models.ErrorResponse
+// @Failure      404           {object}  models.ErrorResponse
+// @Failure      409           {object}  models.ErrorResponse
+// @Failure      500           {object}  models.ErrorResponse
+// @Security     BearerAuth
+// @Router       /accounts/transfer/{from_account}/{to_account}/quote [post]
+func QuoteTransfer(c *gin.Context) {
+	var request models.TransactionRequest
+	if err := c.ShouldBindJSON(&request); err != nil || !request.Amount.IsPositive() {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Invalid or missing amount"})
+		return
+	}
+
+	var fromAccount models.Account
+	if err := config.DB.Where("account_no = ?", c.Param("from_account")).First(&fromAccount).Error; err != nil || !authz.CanTransferFrom(principal(c), fromAccount) {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{Message: "You do not have access to this account"})
+		return
+	}
+	if !amountInAccountCurrency(c, request, fromAccount) {
+		return
+	}
+
+	var toAccount models.Account
+	if err := config.DB.Where("account_no = ?", c.Param("to_account")).First(&toAccount).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Message: "Receiver account not found"})
+		return
+	}
+	if fromAccount.AccountNo == toAccount.AccountNo {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Cannot transfer to the same account"})
+		return
+	}
+	if !accountPermits(c, fromAccount, lifecycle.TransferOut) || !accountPermits(c, toAccount, lifecycle.TransferIn) {
+		return
+	}
+
+	conversion, credited, ok := convertForTransfer(c, request.Amount, fromAccount, toAccount)
+	if !ok {
+		return
+	}
+
+	charges, err := fees.ForTransfer(config.DB, &fromAccount, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to assess fees"})
+		return
+	}
+	totalFees, err := fees.Total(charges)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Amount too large"})
+		return
+	}
+	totalDebit, err := request.Amount.Add(totalFees)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Amount too large"})
+		return
+	}
+
+	quote := models.TransferQuoteResponse{
+		Amount:            request.Amount,
+		Currency:          fromAccount.Currency,
+		Fees:              charges,
+		TotalFees:         totalFees,
+		TotalDebit:        totalDebit,
+		Credited:          credited,
+		CreditedCurrency:  toAccount.Currency,
+		SufficientBalance: fromAccount.Balance >= totalDebit,
+	}
+	if quote.Fees == nil {
+		quote.Fees = []models.FeeCharge{}
+	}
+	if conversion != nil {
+		quote.Rate = conversion.Rate.String()
+		quote.Spread = conversion.Spread
+	}
+	c.JSON(http.StatusOK, quote)
+}
+
+// @Summary      Waive fees
+// @Description  Waives one kind of fee, or all fees when kind is empty, for either a user or every account on a product, optionally until a given time
+// @Tags         Fees
+// @Accept       json
+// @Produce      json
+// @Param        request  body      models.FeeWaiverRequest  true  "Waiver"
+// @Success      201      {object}  models.FeeWaiver
+// @Failure      400      {object}  models.ErrorResponse
+// @Failure      500      {object}  models.ErrorResponse
+// @Security     BearerAuth
+// @Router       /admin/fee-waivers [post]
+func CreateFeeWaiver(c *gin.Context) {
+	var req models.FeeWaiverRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	productCode := strings.TrimSpace(req.ProductCode)
+	if (req.UserID == nil) == (productCode == "") {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Give exactly one of user_id and product_code"})
+		return
+	}
+	if req.Kind != "" && !catalog.ValidFeeKind(req.Kind) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Unknown fee kind"})
+		return
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "expires_at must be in the future"})
+		return
+	}
+
+	if req.UserID != nil {
+		var user models.User
+		if err := config.DB.First(&user, *req.UserID).Error; err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "User not found"})
+			return
+		}
+	} else {
+		var product models.AccountProduct
+		if err := config.DB.Unscoped().Where("code = ?", productCode).First(&product).Error; err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Product not found"})
+			return
+		}
+	}
+
+	waiver := models.FeeWaiver{
+		UserID:      req.UserID,
+		ProductCode: productCode,
+		Kind:        req.Kind,
+		Reason:      req.Reason,
+		ExpiresAt:   req.ExpiresAt,
+		CreatedBy:   principal(c).UserID,
+	}
+	if err := config.DB.Create(&waiver).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to create fee waiver"})
+		return
+	}
+	c.JSON(http.StatusCreated, waiver)
+}
+
+// @Summary      List fee waivers
+// @Description  Lists fee waivers that have not expired
+// @Tags         Fees
+// @Produce      json
+// @Success      200  {object}  models.FeeWaiversResponse
+// @Failure      500  {object}  models.ErrorResponse
+// @Security     BearerAuth
+// @Router       /admin/fee-waivers [get]
+func GetFeeWaivers(c *gin.Context) {
+	var waivers []models.FeeWaiver
+	if err := config.DB.Where("expires_at IS NULL OR expires_at > ?", time.Now()).
+		Order("id").
+		Find(&waivers).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to retrieve fee waivers"})
+		return
+	}
+	c.JSON(http.StatusOK, models.FeeWaiversResponse{Waivers: waivers})
+}
+
+// @Summary      Remove a fee waiver
+// @Tags         Fees
+// @Produce      json
+// @Param        id   path      string  true  "Waiver ID"
+// @Success      200  {object}  map[string]string
+// @Failure      404  {object}  models.ErrorResponse
+// @Failure      500  {object}  models.ErrorResponse
+// @Security     BearerAuth
+// @Router       /admin/fee-waivers/{id} [delete]
+func DeleteFeeWaiver(c *gin.Context) {
+	var waiver models.FeeWaiver
+	if err := config.DB.First(&waiver, c.Param("id")).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{Message: "Fee waiver not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to retrieve fee waiver"})
+		}
+		return
+	}
+	if err := config.DB.Delete(&waiver).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to remove fee waiver"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Fee waiver removed"})
+}
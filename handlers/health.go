@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"bank-app/config"
+	"bank-app/events"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HealthCheck reports whether the database and the event publisher are
+// reachable. It responds 503 if either is down. The endpoint is public, so
+// failures are reported only as "down" and the errors are logged.
+func HealthCheck(publisher events.EventPublisher) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		status := http.StatusOK
+		checks := gin.H{"database": "ok", "events": "ok"}
+
+		if err := config.DB.DB().Ping(); err != nil {
+			status = http.StatusServiceUnavailable
+			checks["database"] = "down"
+			log.Printf("Health check: database unreachable: %v\n", err)
+		}
+
+		if reporter, ok := publisher.(events.HealthReporter); ok {
+			if err := reporter.Health(); err != nil {
+				status = http.StatusServiceUnavailable
+				checks["events"] = "down"
+				log.Printf("Health check: event publisher unhealthy: %v\n", err)
+			}
+		}
+
+		c.JSON(status, gin.H{"status": http.StatusText(status), "checks": checks})
+	}
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"bank-app/authz"
+	"bank-app/config"
+	"bank-app/models"
+	"bank-app/money"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+const dateLayout = "2006-01-02"
+
+// @Summary      Get an account's interest history
+// @Description  Lists the daily accruals between from and to, inclusive, with the end-of-day balance, rate and day-count fraction each used, and the monthly payments made from them. Defaults to the start of last month through today.
+// @Tags         Accounts
+// @Produce      json
+// @Param        account_no  path      string  true   "Account number"
+// @Param        from        query     string  false  "First day, YYYY-MM-DD"
+// @Param        to          query     string  false  "Last day, YYYY-MM-DD"
+// @Success      200         {object}  models.InterestHistoryResponse
+// @Failure      400         {object}  models.ErrorResponse
+// @Failure      404         {object}  models.ErrorResponse
+// @Failure      500         {object}  models.ErrorResponse
+// @Security     BearerAuth
+// @Router       /accounts/{account_no}/interest [get]
+func GetInterestHistory(c *gin.Context) {
+	var account models.Account
+	if err := config.DB.Where("account_no = ?", c.Param("account_no")).First(&account).Error; err != nil || !authz.CanViewAccount(principal(c), account) {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Message: "Account not found"})
+		return
+	}
+
+	now := time.Now()
+	from := time.Date(now.Year(), now.Month()-1, 1, 0, 0, 0, 0, time.Local)
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	for param, date := range map[string]*time.Time{"from": &from, "to": &to} {
+		if value := c.Query(param); value != "" {
+			parsed, err := time.ParseInLocation(dateLayout, value, time.Local)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Invalid " + param + " date, expected YYYY-MM-DD"})
+				return
+			}
+			*date = parsed
+		}
+	}
+	if to.Before(from) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "from must not be after to"})
+		return
+	}
+
+	var product models.AccountProduct
+	if err := config.DB.Unscoped().
+		Preload("InterestTiers", func(db *gorm.DB) *gorm.DB { return db.Order("min_balance_minor") }).
+		Where("code = ?", account.AccountType).
+		First(&product).Error; err != nil && !gorm.IsRecordNotFoundError(err) {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to retrieve interest history"})
+		return
+	}
+
+	response := models.InterestHistoryResponse{
+		AccountNo:     account.AccountNo,
+		Currency:      account.Currency,
+		Product:       product.Name,
+		DayCount:      product.InterestDayCount,
+		InterestTiers: product.InterestTiers,
+	}
+
+	if err := config.DB.Where("account_id = ? AND accrual_date BETWEEN ? AND ?", account.ID, from, to).
+		Order("accrual_date").
+		Find(&response.Accruals).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to retrieve interest history"})
+		return
+	}
+	if err := config.DB.Where("account_id = ? AND period_end >= ? AND period_start <= ?", account.ID, from, to).
+		Order("period_start").
+		Find(&response.Capitalizations).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to retrieve interest history"})
+		return
+	}
+
+	var pending struct{ Total money.Micros }
+	if err := config.DB.Model(&models.InterestAccrual{}).
+		Select("COALESCE(SUM(accrued_micros), 0) AS total").
+		Where("account_id = ? AND capitalization_id IS NULL", account.ID).
+		Scan(&pending).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to retrieve interest history"})
+		return
+	}
+	response.Pending = pending.Total
+
+	c.JSON(http.StatusOK, response)
+}
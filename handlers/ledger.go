@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"bank-app/config"
+	"bank-app/ledger"
+	"bank-app/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+// @Summary      List ledger accounts
+// @Description  Lists every general ledger account with its balance derived from postings
+// @Tags         Ledger
+// @Produce      json
+// @Success      200  {array}   models.LedgerAccountBalance
+// @Failure      500  {object}  models.ErrorResponse
+// @Security     BearerAuth
+// @Router       /ledger/accounts [get]
+func GetLedgerAccounts(c *gin.Context) {
+	var accounts []models.LedgerAccount
+	if err := config.DB.Order("code").Find(&accounts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to retrieve ledger accounts"})
+		return
+	}
+
+	balances := make([]models.LedgerAccountBalance, 0, len(accounts))
+	for i := range accounts {
+		balance, err := ledger.Balance(config.DB, &accounts[i])
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to compute ledger balance"})
+			return
+		}
+		balances = append(balances, models.LedgerAccountBalance{LedgerAccount: accounts[i], Balance: balance})
+	}
+
+	c.JSON(http.StatusOK, balances)
+}
+
+// @Summary      Get a journal entry
+// @Description  Retrieves a journal entry with its postings
+// @Tags         Ledger
+// @Produce      json
+// @Param        id   path      string  true  "Journal entry ID"
+// @Success      200  {object}  models.JournalEntry
+// @Failure      404  {object}  models.ErrorResponse
+// @Failure      500  {object}  models.ErrorResponse
+// @Security     BearerAuth
+// @Router       /ledger/entries/{id} [get]
+func GetJournalEntry(c *gin.Context) {
+	var entry models.JournalEntry
+	if err := config.DB.Preload("Postings").First(&entry, c.Param("id")).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{Message: "Journal entry not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to retrieve journal entry"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, entry)
+}
+
+// @Summary      Trial balance
+// @Description  Totals all debits and credits per currency
+// @Tags         Ledger
+// @Produce      json
+// @Success      200  {array}   ledger.TrialBalanceLine
+// @Failure      500  {object}  models.ErrorResponse
+// @Security     BearerAuth
+// @Router       /ledger/trial-balance [get]
+func GetTrialBalance(c *gin.Context) {
+	lines, err := ledger.TrialBalance(config.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to compute trial balance"})
+		return
+	}
+
+	c.JSON(http.StatusOK, lines)
+}
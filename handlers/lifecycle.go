@@ -0,0 +1,365 @@
+package handlers
+
+import (
+	"bank-app/authz"
+	"bank-app/config"
+	"bank-app/interest"
+	"bank-app/ledger"
+	"bank-app/lifecycle"
+	"bank-app/models"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+var operationNames = map[string]string{
+	lifecycle.Deposit:     "deposits",
+	lifecycle.Withdraw:    "withdrawals",
+	lifecycle.TransferOut: "outgoing transfers",
+	lifecycle.TransferIn:  "incoming transfers",
+}
+
+// accountPermits rejects the request if the account's status does not allow
+// operation. Call it on a locked account so the status cannot change before
+// the money moves.
+func accountPermits(c *gin.Context, account models.Account, operation string) bool {
+	if lifecycle.Permits(account.Status, operation) {
+		return true
+	}
+	c.JSON(http.StatusConflict, models.ErrorResponse{
+		Message: "Account " + account.AccountNo + " is " + account.Status + " and does not accept " + operationNames[operation],
+	})
+	return false
+}
+
+// changeAccountStatus moves a locked account to status and records the
+// change in its history.
+func changeAccountStatus(tx *gorm.DB, account *models.Account, status, reason string, changedBy uint) error {
+	if err := lifecycle.CanTransition(account.Status, status); err != nil {
+		return err
+	}
+
+	// Updates writes the new status into account, so keep the old one first
+	from := account.Status
+	now := time.Now()
+	if err := tx.Model(account).Updates(map[string]interface{}{
+		"status":            status,
+		"status_reason":     reason,
+		"status_changed_at": &now,
+	}).Error; err != nil {
+		return err
+	}
+	return tx.Create(&models.AccountStatusChange{
+		AccountID:  account.ID,
+		FromStatus: from,
+		ToStatus:   status,
+		Reason:     reason,
+		ChangedBy:  changedBy,
+	}).Error
+}
+
+// @Summary      Close an account
+// @Description  Permanently closes one of the user's accounts after paying in any interest accrued so far. A remaining balance is paid out to another of the user's accounts in the same currency, which must then be named. A frozen account's balance cannot be paid out, so it can only be closed once empty.
+// @Tags         Accounts
+// @Accept       json
+// @Produce      json
+// @Param        account_no  path      string                      true   "Account number"
+// @Param        request     body      models.CloseAccountRequest  false  "Payout account"
+// @Success      200         {object}  models.AccountStatusResponse
+// @Failure      400         {object}  models.ErrorResponse
+// @Failure      403         {object}  models.ErrorResponse
+// @Failure      409         {object}  models.ErrorResponse
+// @Failure      500         {object}  models.ErrorResponse
+// @Security     BearerAuth
+// @Router       /accounts/{account_no}/close [post]
+func CloseAccount(c *gin.Context) {
+	var request models.CloseAccountRequest
+	if err := c.ShouldBindJSON(&request); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Invalid input"})
+		return
+	}
+
+	p := principal(c)
+	var account models.Account
+	if err := config.DB.Where("account_no = ?", c.Param("account_no")).First(&account).Error; err != nil || !authz.CanWithdraw(p, account) {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{Message: "Account not found or access denied"})
+		return
+	}
+
+	// The payout must stay with the owner and needs no conversion
+	var payout *models.Account
+	if request.PayoutAccountNo != "" {
+		payout = &models.Account{}
+		if err := config.DB.Where("account_no = ?", request.PayoutAccountNo).First(payout).Error; err != nil || payout.UserID != account.UserID {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Payout account must be another of your accounts"})
+			return
+		}
+		if payout.ID == account.ID {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Payout account must be another of your accounts"})
+			return
+		}
+		if payout.Currency != account.Currency {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Payout account must be in " + string(account.Currency)})
+			return
+		}
+	}
+
+	tx := config.DB.Begin()
+	locked := []*models.Account{&account}
+	if payout != nil {
+		locked = append(locked, payout)
+	}
+	if err := lockAccounts(tx, locked...); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to lock account"})
+		return
+	}
+
+	if err := lifecycle.CanTransition(account.Status, lifecycle.Closed); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusConflict, models.ErrorResponse{Message: "Account is " + account.Status + " and cannot be closed"})
+		return
+	}
+
+	// Interest earned so far is paid in before the balance is settled
+	if err := interest.Settle(tx, &account, time.Now()); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to pay accrued interest"})
+		return
+	}
+	if err := tx.First(&account, account.ID).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to close account"})
+		return
+	}
+
+	if account.Balance.IsPositive() {
+		if account.Status == lifecycle.Frozen {
+			tx.Rollback()
+			c.JSON(http.StatusConflict, models.ErrorResponse{Message: "Account is frozen; its balance cannot be paid out"})
+			return
+		}
+		if payout == nil {
+			tx.Rollback()
+			c.JSON(http.StatusConflict, models.ErrorResponse{Message: "Account has a remaining balance; name a payout account to close it"})
+			return
+		}
+		if !accountPermits(c, *payout, lifecycle.TransferIn) {
+			tx.Rollback()
+			return
+		}
+		if _, err := payout.Balance.Add(account.Balance); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Amount too large"})
+			return
+		}
+		if err := payOutBalance(tx, &account, payout); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to pay out balance"})
+			return
+		}
+	}
+
+	if err := changeAccountStatus(tx, &account, lifecycle.Closed, "Closed at the owner's request", p.UserID); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to close account"})
+		return
+	}
+	if err := tx.First(&account, account.ID).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to close account"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to close account"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.AccountStatusResponse{Message: "Account closed", Account: account})
+}
+
+// payOutBalance transfers the whole balance of a closing account to payout
+// and logs it on both accounts.
+func payOutBalance(tx *gorm.DB, account, payout *models.Account) error {
+	amount := account.Balance
+	journal, err := postMovement(tx, "closure_payout", "Closing balance of "+account.AccountNo+" to "+payout.AccountNo, amount,
+		func() (*models.LedgerAccount, error) { return ledger.CustomerAccount(tx, account) },
+		func() (*models.LedgerAccount, error) { return ledger.CustomerAccount(tx, payout) },
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, accountID := range []uint{account.ID, payout.ID} {
+		if err := tx.Create(&models.Transaction{
+			TransactionType: "transfer",
+			Amount:          amount,
+			Currency:        account.Currency,
+			AccountID:       accountID,
+			FromAccountID:   &account.ID,
+			ToAccountID:     &payout.ID,
+			Status:          "success",
+			JournalEntryID:  &journal.ID,
+			TransactionDate: time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setAccountStatus applies an administrative status change to the account in
+// the path.
+func setAccountStatus(c *gin.Context, status, reason, message string) {
+	var account models.Account
+	if err := config.DB.Where("account_no = ?", c.Param("account_no")).First(&account).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{Message: "Account not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to retrieve account"})
+		}
+		return
+	}
+
+	tx := config.DB.Begin()
+	if err := lockAccounts(tx, &account); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to lock account"})
+		return
+	}
+
+	// Closing here bypasses the payout, so only empty accounts qualify
+	if status == lifecycle.Closed && !account.Balance.IsZero() {
+		tx.Rollback()
+		c.JSON(http.StatusConflict, models.ErrorResponse{Message: "Account has a remaining balance and cannot be closed"})
+		return
+	}
+
+	if err := changeAccountStatus(tx, &account, status, reason, principal(c).UserID); err != nil {
+		tx.Rollback()
+		if errors.Is(err, lifecycle.ErrInvalidTransition) {
+			c.JSON(http.StatusConflict, models.ErrorResponse{Message: "Account is " + account.Status + " and cannot become " + status})
+		} else {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to update account status"})
+		}
+		return
+	}
+	if err := tx.First(&account, account.ID).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to update account status"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to update account status"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.AccountStatusResponse{Message: message, Account: account})
+}
+
+// @Summary      Freeze an account
+// @Description  Blocks all money movement on an account until it is unfrozen
+// @Tags         Accounts
+// @Accept       json
+// @Produce      json
+// @Param        account_no  path      string                      true  "Account number"
+// @Param        request     body      models.StatusReasonRequest  true  "Reason"
+// @Success      200         {object}  models.AccountStatusResponse
+// @Failure      400         {object}  models.ErrorResponse
+// @Failure      404         {object}  models.ErrorResponse
+// @Failure      409         {object}  models.ErrorResponse
+// @Failure      500         {object}  models.ErrorResponse
+// @Security     BearerAuth
+// @Router       /admin/accounts/{account_no}/freeze [post]
+func FreezeAccount(c *gin.Context) {
+	var request models.StatusReasonRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: err.Error()})
+		return
+	}
+	setAccountStatus(c, lifecycle.Frozen, request.Reason, "Account frozen")
+}
+
+// @Summary      Unfreeze an account
+// @Description  Returns a frozen account to active
+// @Tags         Accounts
+// @Accept       json
+// @Produce      json
+// @Param        account_no  path      string                      true  "Account number"
+// @Param        request     body      models.StatusReasonRequest  true  "Reason"
+// @Success      200         {object}  models.AccountStatusResponse
+// @Failure      400         {object}  models.ErrorResponse
+// @Failure      404         {object}  models.ErrorResponse
+// @Failure      409         {object}  models.ErrorResponse
+// @Failure      500         {object}  models.ErrorResponse
+// @Security     BearerAuth
+// @Router       /admin/accounts/{account_no}/unfreeze [post]
+func UnfreezeAccount(c *gin.Context) {
+	var request models.StatusReasonRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: err.Error()})
+		return
+	}
+	var account models.Account
+	if err := config.DB.Where("account_no = ?", c.Param("account_no")).First(&account).Error; err == nil && account.Status != lifecycle.Frozen {
+		c.JSON(http.StatusConflict, models.ErrorResponse{Message: "Account is not frozen"})
+		return
+	}
+	setAccountStatus(c, lifecycle.Active, request.Reason, "Account unfrozen")
+}
+
+// @Summary      Change an account's status
+// @Description  Moves an account to pending, active, frozen, dormant or closed, subject to the allowed transitions. Accounts with a balance cannot be closed this way.
+// @Tags         Accounts
+// @Accept       json
+// @Produce      json
+// @Param        account_no  path      string                       true  "Account number"
+// @Param        request     body      models.AccountStatusRequest  true  "New status and reason"
+// @Success      200         {object}  models.AccountStatusResponse
+// @Failure      400         {object}  models.ErrorResponse
+// @Failure      404         {object}  models.ErrorResponse
+// @Failure      409         {object}  models.ErrorResponse
+// @Failure      500         {object}  models.ErrorResponse
+// @Security     BearerAuth
+// @Router       /admin/accounts/{account_no}/status [put]
+func UpdateAccountStatus(c *gin.Context) {
+	var request models.AccountStatusRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: err.Error()})
+		return
+	}
+	if !lifecycle.Valid(request.Status) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Invalid status"})
+		return
+	}
+	setAccountStatus(c, request.Status, request.Reason, "Account status updated")
+}
+
+// @Summary      Get an account's status history
+// @Description  Lists every status change on an account, newest first
+// @Tags         Accounts
+// @Produce      json
+// @Param        account_no  path      string  true  "Account number"
+// @Success      200         {array}   models.AccountStatusChange
+// @Failure      404         {object}  models.ErrorResponse
+// @Failure      500         {object}  models.ErrorResponse
+// @Security     BearerAuth
+// @Router       /accounts/{account_no}/status-history [get]
+func GetAccountStatusHistory(c *gin.Context) {
+	var account models.Account
+	if err := config.DB.Where("account_no = ?", c.Param("account_no")).First(&account).Error; err != nil || !authz.CanViewAccount(principal(c), account) {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Message: "Account not found"})
+		return
+	}
+
+	var changes []models.AccountStatusChange
+	if err := config.DB.Where("account_id = ?", account.ID).Order("id desc").Find(&changes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to retrieve status history"})
+		return
+	}
+	c.JSON(http.StatusOK, changes)
+}
@@ -0,0 +1,329 @@
+package handlers
+
+import (
+	"bank-app/config"
+	"bank-app/events"
+	"bank-app/models"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	throttleAccount = "account"
+	throttleIP      = "ip"
+	// Requests for verification and password reset emails are throttled
+	// apart from logins, so they cannot lock anyone out of logging in
+	throttleEmail   = "email"
+	throttleEmailIP = "email_ip"
+)
+
+// throttleWait is the remaining lockout, or failing that the remaining
+// backoff after the most recent failure.
+func throttleWait(throttle models.LoginThrottle, policy config.LoginPolicy, now time.Time) time.Duration {
+	if throttle.LockedUntil != nil && throttle.LockedUntil.After(now) {
+		return throttle.LockedUntil.Sub(now)
+	}
+	if throttle.LastFailureAt == nil || throttle.Failures == 0 || now.Sub(*throttle.LastFailureAt) > policy.FailureWindow {
+		return 0
+	}
+
+	backoff := policy.BaseBackoff
+	for i := 1; i < throttle.Failures && backoff < policy.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > policy.MaxBackoff {
+		backoff = policy.MaxBackoff
+	}
+	if d := throttle.LastFailureAt.Add(backoff).Sub(now); d > 0 {
+		return d
+	}
+	return 0
+}
+
+// countLoginAttempt counts an attempt to prove the identity of email, by
+// password or code, against the account and the client's IP address before
+// the attempt is checked, so a burst of parallel guesses cannot all get past
+// the throttle before any of them is counted. It writes a response and
+// returns false if the client must wait. user is nil when no account has
+// that email; the attempt still counts so that probing for accounts is
+// throttled the same way. Attempts that turn out to be right are taken back
+// with loginSucceeded or refundLoginAttempt.
+func countLoginAttempt(c *gin.Context, email string, user *models.User) bool {
+	wait, err := addLoginAttempt(c, email, user)
+	if err != nil {
+		log.Printf("Failed to count login attempt: %v\n", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to check login attempts"})
+		return false
+	}
+	if wait <= 0 {
+		return true
+	}
+
+	seconds := int(wait.Round(time.Second).Seconds())
+	if seconds < 1 {
+		seconds = 1
+	}
+	c.Header("Retry-After", fmt.Sprint(seconds))
+	c.JSON(http.StatusTooManyRequests, models.ErrorResponse{Message: "Too many failed login attempts, try again later"})
+	return false
+}
+
+// addLoginAttempt locks the throttles for email and the client's IP address
+// and, unless either makes the client wait, counts the attempt against both,
+// locking either out once it reaches its limit. It returns how long the
+// client must wait; the attempt is not counted then.
+func addLoginAttempt(c *gin.Context, email string, user *models.User) (time.Duration, error) {
+	policy := config.LoadLoginPolicy()
+	ip := c.ClientIP()
+	now := time.Now()
+
+	// Always account then IP address, so concurrent attempts lock the rows
+	// in the same order
+	tx := config.DB.Begin()
+	account, err := lockThrottle(tx, throttleAccount, normalizeEmail(email))
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	address, err := lockThrottle(tx, throttleIP, ip)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	wait := throttleWait(*account, policy, now)
+	if d := throttleWait(*address, policy, now); d > wait {
+		wait = d
+	}
+	if wait > 0 {
+		tx.Rollback()
+		return wait, nil
+	}
+
+	locked, err := addFailure(tx, account, policy.MaxAccountFailures, policy, now)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	if locked && user != nil {
+		if err := enqueueEvent(c, tx, &events.AccountLocked{
+			UserID:      user.ID,
+			Failures:    account.Failures,
+			LockedUntil: *account.LockedUntil,
+			IPAddress:   ip,
+			ToEmail:     user.Email,
+		}); err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+	}
+
+	locked, err = addFailure(tx, address, policy.MaxIPFailures, policy, now)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	if locked {
+		if err := enqueueEvent(c, tx, &events.IPLocked{
+			IPAddress:   ip,
+			Failures:    address.Failures,
+			LockedUntil: *address.LockedUntil,
+		}); err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+	}
+
+	return 0, tx.Commit().Error
+}
+
+// countEmailRequest counts a request to send a verification or password
+// reset email to email against the address and the client's IP address, so
+// the endpoints cannot be used to flood an inbox. It writes a response and
+// returns false if the client must wait. Requests count whether or not an
+// account has the address, so a 429 reveals nothing about which ones exist.
+func countEmailRequest(c *gin.Context, email string) bool {
+	wait, err := addEmailRequest(c, email)
+	if err != nil {
+		log.Printf("Failed to count email request: %v\n", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to check recent requests"})
+		return false
+	}
+	if wait <= 0 {
+		return true
+	}
+
+	seconds := int(wait.Round(time.Second).Seconds())
+	if seconds < 1 {
+		seconds = 1
+	}
+	c.Header("Retry-After", fmt.Sprint(seconds))
+	c.JSON(http.StatusTooManyRequests, models.ErrorResponse{Message: "Too many requests, try again later"})
+	return false
+}
+
+// addEmailRequest is addLoginAttempt for email requests, under the email
+// request policy and without lockout notifications.
+func addEmailRequest(c *gin.Context, email string) (time.Duration, error) {
+	policy := config.LoadEmailRequestPolicy()
+	now := time.Now()
+
+	tx := config.DB.Begin()
+	address, err := lockThrottle(tx, throttleEmail, normalizeEmail(email))
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	ip, err := lockThrottle(tx, throttleEmailIP, c.ClientIP())
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	wait := throttleWait(*address, policy, now)
+	if d := throttleWait(*ip, policy, now); d > wait {
+		wait = d
+	}
+	if wait > 0 {
+		tx.Rollback()
+		return wait, nil
+	}
+
+	if _, err := addFailure(tx, address, policy.MaxAccountFailures, policy, now); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	if _, err := addFailure(tx, ip, policy.MaxIPFailures, policy, now); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	return 0, tx.Commit().Error
+}
+
+// lockThrottle returns the throttle for one subject, locked by tx. The row is
+// created with an upsert: FirstOrCreate under FOR UPDATE deadlocks or fails
+// on the unique index when two first attempts race to create it.
+func lockThrottle(tx *gorm.DB, scope, subject string) (*models.LoginThrottle, error) {
+	now := time.Now()
+	if err := tx.Exec(
+		"INSERT INTO login_throttles (created_at, updated_at, scope, subject, failures) VALUES (?, ?, ?, ?, 0) "+
+			"ON DUPLICATE KEY UPDATE updated_at = updated_at",
+		now, now, scope, subject,
+	).Error; err != nil {
+		return nil, err
+	}
+
+	var throttle models.LoginThrottle
+	err := tx.Set("gorm:query_option", "FOR UPDATE").
+		Where("scope = ? AND subject = ?", scope, subject).
+		First(&throttle).Error
+	return &throttle, err
+}
+
+// addFailure increments the failure count of a locked throttle and reports
+// whether this failure locked it.
+func addFailure(tx *gorm.DB, throttle *models.LoginThrottle, limit int, policy config.LoginPolicy, now time.Time) (bool, error) {
+	// Start counting afresh once a lockout has run out or the last failure
+	// is outside the window
+	if (throttle.LockedUntil != nil && !throttle.LockedUntil.After(now)) ||
+		(throttle.LastFailureAt != nil && now.Sub(*throttle.LastFailureAt) > policy.FailureWindow) {
+		throttle.Failures = 0
+		throttle.LockedUntil = nil
+	}
+
+	throttle.Failures++
+	throttle.LastFailureAt = &now
+
+	locked := false
+	if throttle.Failures >= limit && throttle.LockedUntil == nil {
+		until := now.Add(policy.LockoutDuration)
+		throttle.LockedUntil = &until
+		locked = true
+	}
+
+	err := tx.Model(throttle).Updates(map[string]interface{}{
+		"failures":        throttle.Failures,
+		"last_failure_at": throttle.LastFailureAt,
+		"locked_until":    throttle.LockedUntil,
+	}).Error
+	return locked, err
+}
+
+// loginSucceeded forgets the failed attempts against email and takes the
+// successful attempt back off the client's IP address, whose earlier failures
+// stay so that one valid login cannot reset an attacker's count.
+func loginSucceeded(c *gin.Context, email string) {
+	if err := clearLoginFailures(config.DB, email); err != nil {
+		log.Printf("Failed to clear login failures: %v\n", err)
+	}
+	if err := uncountAttempt(config.DB, throttleIP, c.ClientIP()); err != nil {
+		log.Printf("Failed to clear login failures: %v\n", err)
+	}
+}
+
+// refundLoginAttempt takes a right answer back off both counts without
+// forgetting earlier failures, for a password that does not finish a login
+// on its own, such as one that only earns a second factor challenge.
+func refundLoginAttempt(c *gin.Context, email string) {
+	for _, throttle := range []struct{ scope, subject string }{
+		{throttleAccount, normalizeEmail(email)},
+		{throttleIP, c.ClientIP()},
+	} {
+		if err := uncountAttempt(config.DB, throttle.scope, throttle.subject); err != nil {
+			log.Printf("Failed to clear login failures: %v\n", err)
+		}
+	}
+}
+
+func uncountAttempt(db *gorm.DB, scope, subject string) error {
+	return db.Model(&models.LoginThrottle{}).
+		Where("scope = ? AND subject = ? AND failures > 0", scope, subject).
+		UpdateColumn("failures", gorm.Expr("failures - 1")).Error
+}
+
+// clearLoginFailures forgets failed attempts against email. The IP address
+// count is left alone so one valid login cannot reset an attacker's count.
+func clearLoginFailures(db *gorm.DB, email string) error {
+	return db.Unscoped().
+		Where("scope = ? AND subject = ?", throttleAccount, normalizeEmail(email)).
+		Delete(&models.LoginThrottle{}).Error
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// @Summary      Unlock a user's login
+// @Description  Clears failed login attempts and any lockout on the user's account
+// @Tags         Users
+// @Produce      json
+// @Param        id   path      string  true  "User ID"
+// @Success      200  {object}  map[string]string
+// @Failure      404  {object}  models.ErrorResponse
+// @Failure      500  {object}  models.ErrorResponse
+// @Security     BearerAuth
+// @Router       /admin/users/{id}/unlock [post]
+func UnlockUser(c *gin.Context) {
+	var user models.User
+	if err := config.DB.First(&user, c.Param("id")).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{Message: "User not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to retrieve user"})
+		}
+		return
+	}
+
+	if err := clearLoginFailures(config.DB, user.Email); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to unlock user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User unlocked"})
+}
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"bank-app/config"
+	"bank-app/models"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// A burst of parallel wrong passwords must not get more guesses than the
+// lockout allows, however the requests interleave.
+func TestParallelBadLoginsStillLockOut(t *testing.T) {
+	openTestDB(t)
+	gin.SetMode(gin.TestMode)
+
+	const limit = 5
+	t.Setenv("LOGIN_MAX_FAILURES", fmt.Sprint(limit))
+	t.Setenv("LOGIN_IP_MAX_FAILURES", "1000")
+	// Without backoff only the lockout stands between the guesses
+	t.Setenv("LOGIN_BASE_BACKOFF", "1ns")
+	t.Setenv("LOGIN_MAX_BACKOFF", "1ns")
+
+	now := time.Now()
+	hash, err := bcrypt.GenerateFromPassword([]byte("Correct-horse-1"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := models.User{
+		FirstName:       "Lockout",
+		LastName:        "Test",
+		Email:           fmt.Sprintf("lockout-%d@example.com", now.UnixNano()),
+		Password:        string(hash),
+		Role:            "customer",
+		EmailVerifiedAt: &now,
+	}
+	if err := config.DB.Create(&user).Error; err != nil {
+		t.Fatalf("creating user: %v", err)
+	}
+	// Each run comes from its own address so earlier runs do not count
+	remoteAddr := fmt.Sprintf("10.%d.%d.%d:40000", now.Nanosecond()>>16&0xff, now.Nanosecond()>>8&0xff, now.Nanosecond()&0xff)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set("correlationID", "lockout-test") })
+	router.POST("/login", Login)
+
+	login := func(password string) int {
+		body := fmt.Sprintf(`{"email":%q,"password":%q}`, user.Email, password)
+		req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	const attempts = 4 * limit
+	var wg sync.WaitGroup
+	codes := make(chan int, attempts)
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes <- login("wrong-password")
+		}()
+	}
+	wg.Wait()
+	close(codes)
+
+	counts := map[int]int{}
+	for code := range codes {
+		counts[code]++
+	}
+	if counts[http.StatusUnauthorized] != limit || counts[http.StatusTooManyRequests] != attempts-limit {
+		t.Errorf("responses = %v, want %d x 401 and %d x 429", counts, limit, attempts-limit)
+	}
+
+	// The lockout holds even for the right password
+	if code := login("Correct-horse-1"); code != http.StatusTooManyRequests {
+		t.Errorf("right password during lockout: got %d, want 429", code)
+	}
+
+	var throttle models.LoginThrottle
+	if err := config.DB.Where("scope = ? AND subject = ?", throttleAccount, normalizeEmail(user.Email)).First(&throttle).Error; err != nil {
+		t.Fatalf("loading throttle: %v", err)
+	}
+	if throttle.Failures != limit || throttle.LockedUntil == nil {
+		t.Errorf("throttle = %d failures, locked until %v; want %d and locked", throttle.Failures, throttle.LockedUntil, limit)
+	}
+}
+
+// Reset emails are limited per address, whether or not an account has it.
+func TestForgotPasswordIsThrottled(t *testing.T) {
+	openTestDB(t)
+	gin.SetMode(gin.TestMode)
+
+	const limit = 3
+	t.Setenv("EMAIL_MAX_REQUESTS", fmt.Sprint(limit))
+	t.Setenv("EMAIL_BASE_BACKOFF", "1ns")
+	t.Setenv("EMAIL_MAX_BACKOFF", "1ns")
+
+	now := time.Now()
+	email := fmt.Sprintf("nobody-%d@example.com", now.UnixNano())
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set("correlationID", "lockout-test") })
+	router.POST("/password/forgot", ForgotPassword)
+
+	forgot := func(remoteAddr string) int {
+		req := httptest.NewRequest(http.MethodPost, "/password/forgot", strings.NewReader(fmt.Sprintf(`{"email":%q}`, email)))
+		req.Header.Set("Content-Type", "application/json")
+		req.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// Spread over addresses so only the per-address limit applies
+	for i := 0; i < limit; i++ {
+		if code := forgot(fmt.Sprintf("10.1.%d.%d:40000", now.Nanosecond()&0xff, i)); code != http.StatusAccepted {
+			t.Fatalf("request %d: got %d, want 202", i+1, code)
+		}
+	}
+	if code := forgot(fmt.Sprintf("10.2.%d.1:40000", now.Nanosecond()&0xff)); code != http.StatusTooManyRequests {
+		t.Errorf("request over the limit: got %d, want 429", code)
+	}
+
+	// Login attempts are counted separately
+	var throttle models.LoginThrottle
+	if err := config.DB.Where("scope = ? AND subject = ?", throttleAccount, email).First(&throttle).Error; err == nil {
+		t.Errorf("email requests counted as %d failed logins", throttle.Failures)
+	}
+}
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"bank-app/config"
+	"bank-app/models"
+	"bank-app/password"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// setPassword replaces user's password with newPassword after checking it
+// against the policy and the user's recent passwords. Policy violations are
+// returned as *password.PolicyError.
+func setPassword(tx *gorm.DB, user *models.User, newPassword string) error {
+	policy := config.LoadPasswordPolicy()
+	if err := policy.Check(newPassword); err != nil {
+		return err
+	}
+
+	// The current password counts as the first entry of the history; a
+	// history size of 0 turns the reuse check off altogether
+	var previous []string
+	if policy.HistorySize > 0 {
+		previous = append(previous, user.Password)
+	}
+	if policy.HistorySize > 1 {
+		var history []models.PasswordHistory
+		if err := tx.Where("user_id = ?", user.ID).Order("id desc").Limit(policy.HistorySize - 1).Find(&history).Error; err != nil {
+			return err
+		}
+		for _, entry := range history {
+			previous = append(previous, entry.PasswordHash)
+		}
+	}
+	for _, hash := range previous {
+		if bcrypt.CompareHashAndPassword([]byte(hash), []byte(newPassword)) == nil {
+			return &password.PolicyError{Problems: []string{"was used recently"}}
+		}
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Create(&models.PasswordHistory{UserID: user.ID, PasswordHash: user.Password}).Error; err != nil {
+		return err
+	}
+	if err := prunePasswordHistory(tx, user.ID, max(policy.HistorySize-1, 0)); err != nil {
+		return err
+	}
+	if err := tx.Model(user).Update("password", string(hashedPassword)).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// prunePasswordHistory deletes all but the newest keep entries.
+func prunePasswordHistory(tx *gorm.DB, userID uint, keep int) error {
+	var stale []models.PasswordHistory
+	if err := tx.Select("id").Where("user_id = ?", userID).Order("id desc").Offset(keep).Limit(1000).Find(&stale).Error; err != nil {
+		return err
+	}
+	if len(stale) == 0 {
+		return nil
+	}
+
+	ids := make([]uint, 0, len(stale))
+	for _, entry := range stale {
+		ids = append(ids, entry.ID)
+	}
+	return tx.Unscoped().Where("id IN (?)", ids).Delete(&models.PasswordHistory{}).Error
+}
+
+// passwordError writes the response for an error from setPassword.
+func passwordError(c *gin.Context, err error) {
+	var policyErr *password.PolicyError
+	if errors.As(err, &policyErr) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: policyErr.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to update password"})
+}
+
+// @Summary      Change password
+// @Description  Changes the current user's password. The new password must satisfy the password policy and differ from recent ones. Every other session is logged out and every API key revoked.
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        request  body      models.ChangePasswordRequest  true  "Current and new password"
+// @Success      200      {object}  map[string]string
+// @Failure      400      {object}  models.ErrorResponse
+// @Failure      403      {object}  models.ErrorResponse
+// @Failure      429      {object}  models.ErrorResponse
+// @Failure      500      {object}  models.ErrorResponse
+// @Security     BearerAuth
+// @Router       /users/me/password [put]
+func ChangePassword(c *gin.Context) {
+	var req models.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, c.MustGet("userID").(uint)).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Message: "User not found"})
+		return
+	}
+
+	// Guessing the current password is throttled like logging in
+	if !countLoginAttempt(c, user.Email, &user) {
+		return
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.CurrentPassword)); err != nil {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{Message: "Current password is incorrect"})
+		return
+	}
+	refundLoginAttempt(c, user.Email)
+
+	tx := config.DB.Begin()
+	if err := tx.Set("gorm:query_option", "FOR UPDATE").First(&user, user.ID).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to update password"})
+		return
+	}
+	if err := setPassword(tx, &user, req.NewPassword); err != nil {
+		tx.Rollback()
+		passwordError(c, err)
+		return
+	}
+
+	now := time.Now()
+	if err := tx.Model(&models.Session{}).
+		Where("user_id = ? AND id <> ? AND revoked_at IS NULL", user.ID, c.MustGet("sessionID").(uint)).
+		Updates(map[string]interface{}{"revoked_at": &now, "revoked_reason": "password changed"}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to revoke other sessions"})
+		return
+	}
+	if err := revokeAPIKeys(tx, user.ID); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to revoke API keys"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to update password"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password changed successfully"})
+}
@@ -0,0 +1,20 @@
+package handlers
+
+import (
+	"bank-app/authz"
+
+	"github.com/gin-gonic/gin"
+)
+
+// principal returns the authenticated caller set by AuthMiddleware.
+func principal(c *gin.Context) authz.Principal {
+	p := authz.Principal{
+		UserID: c.MustGet("userID").(uint),
+		Role:   c.GetString("role"),
+	}
+	if scopes, ok := c.Get("scopes"); ok {
+		p.APIKey = true
+		p.Scopes = scopes.([]string)
+	}
+	return p
+}
@@ -0,0 +1,276 @@
+package handlers
+
+import (
+	"bank-app/catalog"
+	"bank-app/config"
+	"bank-app/lifecycle"
+	"bank-app/models"
+	"bank-app/money"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+// loadProduct reads a product that is still on offer, with its rules.
+func loadProduct(db *gorm.DB, code string, product *models.AccountProduct) error {
+	return db.
+		Preload("InterestTiers", func(db *gorm.DB) *gorm.DB { return db.Order("min_balance_minor") }).
+		Preload("FeeRules", func(db *gorm.DB) *gorm.DB { return db.Order("kind") }).
+		Where("code = ?", code).
+		First(product).Error
+}
+
+// productFromRequest builds an unsaved product from a request, leaving
+// validation to catalog.Validate.
+func productFromRequest(code string, req models.AccountProductRequest) models.AccountProduct {
+	product := models.AccountProduct{
+		Code:               strings.TrimSpace(code),
+		Name:               strings.TrimSpace(req.Name),
+		Currency:           money.Currency(strings.ToUpper(strings.TrimSpace(req.Currency))),
+		MinOpeningBalance:  req.MinOpeningBalance,
+		MaxAccountsPerUser: req.MaxAccountsPerUser,
+		InterestDayCount:   req.InterestDayCount,
+	}
+	if product.InterestDayCount == "" {
+		product.InterestDayCount = catalog.DayCountActual365
+	}
+	for _, tier := range req.InterestTiers {
+		product.InterestTiers = append(product.InterestTiers, models.InterestTier{
+			MinBalance: tier.MinBalance,
+			RateBps:    tier.RateBps,
+		})
+	}
+	for _, rule := range req.FeeRules {
+		product.FeeRules = append(product.FeeRules, models.FeeRule{
+			Kind:           rule.Kind,
+			Amount:         rule.Amount,
+			MinimumBalance: rule.MinimumBalance,
+			FreeCount:      rule.FreeCount,
+		})
+	}
+	return product
+}
+
+// saveProductRules writes the product's tiers and fee rules, replacing any
+// it had before.
+func saveProductRules(tx *gorm.DB, product *models.AccountProduct) error {
+	if err := tx.Unscoped().Where("product_id = ?", product.ID).Delete(&models.InterestTier{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Unscoped().Where("product_id = ?", product.ID).Delete(&models.FeeRule{}).Error; err != nil {
+		return err
+	}
+	for i := range product.InterestTiers {
+		product.InterestTiers[i].ProductID = product.ID
+		if err := tx.Create(&product.InterestTiers[i]).Error; err != nil {
+			return err
+		}
+	}
+	for i := range product.FeeRules {
+		product.FeeRules[i].ProductID = product.ID
+		if err := tx.Create(&product.FeeRules[i]).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// @Summary      List account products
+// @Description  Lists the account types that can be opened, with their limits, interest tiers and fees
+// @Tags         Products
+// @Produce      json
+// @Success      200  {object}  models.AccountProductsResponse
+// @Failure      500  {object}  models.ErrorResponse
+// @Security     BearerAuth
+// @Router       /products [get]
+func GetAccountProducts(c *gin.Context) {
+	var products []models.AccountProduct
+	if err := config.DB.
+		Preload("InterestTiers", func(db *gorm.DB) *gorm.DB { return db.Order("min_balance_minor") }).
+		Preload("FeeRules", func(db *gorm.DB) *gorm.DB { return db.Order("kind") }).
+		Order("code").
+		Find(&products).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to retrieve products"})
+		return
+	}
+	c.JSON(http.StatusOK, models.AccountProductsResponse{Products: products})
+}
+
+// @Summary      Get an account product
+// @Tags         Products
+// @Produce      json
+// @Param        code  path      string  true  "Product code"
+// @Success      200   {object}  models.AccountProduct
+// @Failure      404   {object}  models.ErrorResponse
+// @Security     BearerAuth
+// @Router       /products/{code} [get]
+func GetAccountProduct(c *gin.Context) {
+	var product models.AccountProduct
+	if err := loadProduct(config.DB, c.Param("code"), &product); err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Message: "Product not found"})
+		return
+	}
+	c.JSON(http.StatusOK, product)
+}
+
+// @Summary      Create an account product
+// @Description  Adds an account type customers can open. Codes cannot be reused, even after a product is retired.
+// @Tags         Products
+// @Accept       json
+// @Produce      json
+// @Param        request  body      models.AccountProductRequest  true  "Product definition"
+// @Success      201      {object}  models.AccountProduct
+// @Failure      400      {object}  models.ErrorResponse
+// @Failure      409      {object}  models.ErrorResponse
+// @Failure      500      {object}  models.ErrorResponse
+// @Security     BearerAuth
+// @Router       /admin/products [post]
+func CreateAccountProduct(c *gin.Context) {
+	var req models.AccountProductRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Invalid input"})
+		return
+	}
+
+	product := productFromRequest(req.Code, req)
+	if err := catalog.Validate(product); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	var existing models.AccountProduct
+	if err := config.DB.Unscoped().Where("code = ?", product.Code).First(&existing).Error; err == nil {
+		c.JSON(http.StatusConflict, models.ErrorResponse{Message: "Product code already in use"})
+		return
+	}
+
+	tx := config.DB.Begin()
+	if err := tx.Set("gorm:save_associations", false).Create(&product).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to create product"})
+		return
+	}
+	if err := saveProductRules(tx, &product); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to create product"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to create product"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, product)
+}
+
+// @Summary      Update an account product
+// @Description  Replaces a product's settings, interest tiers and fee rules. Existing accounts follow the new rules; limits and the opening balance only affect accounts opened afterwards. The currency cannot change while the product has open accounts.
+// @Tags         Products
+// @Accept       json
+// @Produce      json
+// @Param        code     path      string                        true  "Product code"
+// @Param        request  body      models.AccountProductRequest  true  "Product definition"
+// @Success      200      {object}  models.AccountProduct
+// @Failure      400      {object}  models.ErrorResponse
+// @Failure      404      {object}  models.ErrorResponse
+// @Failure      409      {object}  models.ErrorResponse
+// @Failure      500      {object}  models.ErrorResponse
+// @Security     BearerAuth
+// @Router       /admin/products/{code} [put]
+func UpdateAccountProduct(c *gin.Context) {
+	var req models.AccountProductRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Invalid input"})
+		return
+	}
+
+	var product models.AccountProduct
+	if err := config.DB.Where("code = ?", c.Param("code")).First(&product).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Message: "Product not found"})
+		return
+	}
+
+	updated := productFromRequest(product.Code, req)
+	if err := catalog.Validate(updated); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	// Lock the product so no account can be opened on it between counting
+	// its open accounts and changing its currency
+	tx := config.DB.Begin()
+	if err := tx.Set("gorm:query_option", "FOR UPDATE").First(&product, product.ID).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to update product"})
+		return
+	}
+	if updated.Currency != product.Currency {
+		// Balances and rules of existing accounts are in the old currency
+		var open int
+		if err := tx.Model(&models.Account{}).
+			Where("account_type = ? AND status <> ?", product.Code, lifecycle.Closed).
+			Count(&open).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to update product"})
+			return
+		}
+		if open > 0 {
+			tx.Rollback()
+			c.JSON(http.StatusConflict, models.ErrorResponse{Message: "The currency of a product with open accounts cannot be changed"})
+			return
+		}
+	}
+	if err := tx.Model(&product).Updates(map[string]interface{}{
+		"name":                      updated.Name,
+		"currency":                  updated.Currency,
+		"min_opening_balance_minor": updated.MinOpeningBalance,
+		"max_accounts_per_user":     updated.MaxAccountsPerUser,
+		"interest_day_count":        updated.InterestDayCount,
+	}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to update product"})
+		return
+	}
+	updated.ID = product.ID
+	if err := saveProductRules(tx, &updated); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to update product"})
+		return
+	}
+	if err := loadProduct(tx, product.Code, &product); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to update product"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to update product"})
+		return
+	}
+
+	c.JSON(http.StatusOK, product)
+}
+
+// @Summary      Retire an account product
+// @Description  Stops new accounts being opened on a product. Existing accounts keep it and its rules.
+// @Tags         Products
+// @Produce      json
+// @Param        code  path      string  true  "Product code"
+// @Success      200   {object}  map[string]string
+// @Failure      404   {object}  models.ErrorResponse
+// @Failure      500   {object}  models.ErrorResponse
+// @Security     BearerAuth
+// @Router       /admin/products/{code} [delete]
+func DeleteAccountProduct(c *gin.Context) {
+	var product models.AccountProduct
+	if err := config.DB.Where("code = ?", c.Param("code")).First(&product).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Message: "Product not found"})
+		return
+	}
+	if err := config.DB.Delete(&product).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to retire product"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Product retired"})
+}
@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"bank-app/config"
+	"bank-app/models"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+// startSession opens a login session for user, authenticated by acr, and
+// issues its first access and refresh tokens.
+func startSession(user models.User, acr string) (models.TokenResponse, error) {
+	tx := config.DB.Begin()
+
+	now := time.Now()
+	session := models.Session{UserID: user.ID, AuthTime: &now, ACR: acr}
+	if err := tx.Create(&session).Error; err != nil {
+		tx.Rollback()
+		return models.TokenResponse{}, err
+	}
+
+	tokens, err := issueTokens(tx, session, user.Role)
+	if err != nil {
+		tx.Rollback()
+		return models.TokenResponse{}, err
+	}
+	return tokens, tx.Commit().Error
+}
+
+// issueTokens stores a new refresh token for session and signs a matching
+// access token carrying role.
+func issueTokens(tx *gorm.DB, session models.Session, role string) (models.TokenResponse, error) {
+	refreshToken, hash, err := config.GenerateToken()
+	if err != nil {
+		return models.TokenResponse{}, err
+	}
+	if err := tx.Create(&models.RefreshToken{
+		SessionID: session.ID,
+		TokenHash: hash,
+		ExpiresAt: time.Now().Add(config.RefreshTokenTTL),
+	}).Error; err != nil {
+		return models.TokenResponse{}, err
+	}
+
+	accessToken, err := config.GenerateJWT(session.UserID, session.ID, role, session.AuthTime, session.ACR)
+	if err != nil {
+		return models.TokenResponse{}, err
+	}
+
+	return models.TokenResponse{
+		Token:        accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(config.AccessTokenTTL.Seconds()),
+	}, nil
+}
+
+// revokeSession marks a session revoked so none of its tokens work any more.
+func revokeSession(db *gorm.DB, sessionID uint, reason string) error {
+	now := time.Now()
+	return db.Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL", sessionID).
+		Updates(map[string]interface{}{"revoked_at": &now, "revoked_reason": reason}).Error
+}
+
+// @Summary      Refresh an access token
+// @Description  Exchanges a refresh token for a new access token and a new refresh token. Each refresh token can be used once; reusing one revokes the whole session.
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        request  body      models.RefreshTokenRequest  true  "Refresh token"
+// @Success      200      {object}  models.TokenResponse
+// @Failure      400      {object}  models.ErrorResponse
+// @Failure      401      {object}  models.ErrorResponse
+// @Failure      500      {object}  models.ErrorResponse
+// @Router       /token/refresh [post]
+func RefreshToken(c *gin.Context) {
+	var req models.RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	tx := config.DB.Begin()
+
+	var stored models.RefreshToken
+	if err := tx.Set("gorm:query_option", "FOR UPDATE").
+		Where("token_hash = ?", config.HashToken(req.RefreshToken)).
+		First(&stored).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Message: "Invalid refresh token"})
+		return
+	}
+
+	// A used token coming back means it was copied; end the whole session
+	if stored.UsedAt != nil {
+		if err := revokeSession(tx, stored.SessionID, "refresh token reuse"); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to revoke session"})
+			return
+		}
+		tx.Commit()
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Message: "Refresh token reuse detected, session revoked"})
+		return
+	}
+
+	var session models.Session
+	if err := tx.First(&session, stored.SessionID).Error; err != nil || session.RevokedAt != nil || stored.ExpiresAt.Before(time.Now()) {
+		tx.Rollback()
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Message: "Invalid refresh token"})
+		return
+	}
+
+	now := time.Now()
+	if err := tx.Model(&stored).Update("used_at", &now).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to rotate refresh token"})
+		return
+	}
+
+	// Pick up the user's current role in the new access token
+	var user models.User
+	if err := tx.First(&user, session.UserID).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Message: "Invalid refresh token"})
+		return
+	}
+
+	tokens, err := issueTokens(tx, session, user.Role)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Token generation failed"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Token generation failed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// @Summary      Log out
+// @Description  Revokes the current session, invalidating its access and refresh tokens
+// @Tags         Auth
+// @Produce      json
+// @Success      200  {object}  map[string]string
+// @Failure      500  {object}  models.ErrorResponse
+// @Security     BearerAuth
+// @Router       /logout [post]
+func Logout(c *gin.Context) {
+	sessionID := c.MustGet("sessionID").(uint)
+
+	if err := revokeSession(config.DB, sessionID, "logout"); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to log out"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
+}
+
+// @Summary      JSON Web Key Set
+// @Description  Public keys that verify access tokens, for services that validate them independently
+// @Tags         Auth
+// @Produce      json
+// @Success      200  {object}  map[string]interface{}
+// @Router       /.well-known/jwks.json [get]
+func JWKS(c *gin.Context) {
+	c.JSON(http.StatusOK, config.JWTKeys.JWKS())
+}
@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"bank-app/config"
+	"bank-app/models"
+	"bank-app/money"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// requireStepUp lets amounts up to the step-up threshold for currency
+// through. Above it, the access token must show an authentication within the
+// last StepUpMaxAge; otherwise it writes a 401 challenge and returns false.
+// API key requests above the threshold are refused outright. amount is the
+// whole debit, fees included, so that fees cannot carry a payment over the
+// threshold unchallenged.
+func requireStepUp(c *gin.Context, amount money.Amount, currency money.Currency) bool {
+	threshold := config.StepUpThreshold(currency)
+	if amount <= threshold {
+		return true
+	}
+
+	// API keys cannot re-authenticate, so they are held to the threshold
+	if _, ok := c.Get("apiKeyID"); ok {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{
+			Message: fmt.Sprintf("Amounts above %s cannot be moved with an API key", money.New(threshold, currency)),
+		})
+		return false
+	}
+
+	maxAge := config.StepUpMaxAge()
+	if authTime, ok := c.Get("authTime"); ok && time.Since(authTime.(time.Time)) <= maxAge {
+		return true
+	}
+
+	c.Header("WWW-Authenticate", fmt.Sprintf(
+		`Bearer error="insufficient_user_authentication", error_description="A more recent authentication is required", max_age=%d`,
+		int(maxAge.Seconds())))
+	c.JSON(http.StatusUnauthorized, models.StepUpChallengeResponse{
+		Error:     "step_up_required",
+		Message:   fmt.Sprintf("Amounts above %s require re-authentication", money.New(threshold, currency)),
+		MaxAge:    int(maxAge.Seconds()),
+		ACRValues: []string{config.ACRPassword, config.ACRMFA},
+		ReauthURL: "/reauth",
+	})
+	return false
+}
+
+// @Summary      Re-authenticate the current session
+// @Description  Proves the user's identity again without logging in afresh, refreshing the session's auth_time so that high-value operations are allowed. Users with two-factor authentication enabled must send a TOTP code; others send their password.
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        request  body      models.ReauthenticateRequest  true  "Password or TOTP code"
+// @Success      200      {object}  models.ReauthenticateResponse
+// @Failure      400      {object}  models.ErrorResponse
+// @Failure      401      {object}  models.ErrorResponse
+// @Failure      429      {object}  models.ErrorResponse
+// @Failure      500      {object}  models.ErrorResponse
+// @Security     BearerAuth
+// @Router       /reauth [post]
+func Reauthenticate(c *gin.Context) {
+	var req models.ReauthenticateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	tx := config.DB.Begin()
+	var user models.User
+	if err := tx.Set("gorm:query_option", "FOR UPDATE").First(&user, c.MustGet("userID").(uint)).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Message: "Invalid credentials"})
+		return
+	}
+
+	// Guessing the password or code is throttled like logging in
+	if !countLoginAttempt(c, user.Email, &user) {
+		tx.Rollback()
+		return
+	}
+
+	// A password alone is not enough once the user has a second factor
+	acr := config.ACRPassword
+	if user.TOTPEnabled {
+		if req.Code == "" {
+			tx.Rollback()
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "A TOTP code is required"})
+			return
+		}
+		ok, err := verifyTOTP(tx, &user, req.Code)
+		if err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to verify code"})
+			return
+		}
+		if !ok {
+			tx.Rollback()
+			c.JSON(http.StatusUnauthorized, models.ErrorResponse{Message: "Invalid code"})
+			return
+		}
+		acr = config.ACRMFA
+	} else {
+		if req.Password == "" {
+			tx.Rollback()
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Password is required"})
+			return
+		}
+		if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusUnauthorized, models.ErrorResponse{Message: "Invalid credentials"})
+			return
+		}
+	}
+
+	var session models.Session
+	if err := tx.First(&session, c.MustGet("sessionID").(uint)).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Message: "Session has been revoked"})
+		return
+	}
+	now := time.Now()
+	if err := tx.Model(&session).Updates(map[string]interface{}{"auth_time": &now, "acr": acr}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to update session"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to update session"})
+		return
+	}
+
+	loginSucceeded(c, user.Email)
+
+	// The refresh token is unchanged; tokens refreshed from it carry the new
+	// auth_time because it is read from the session
+	token, err := config.GenerateJWT(session.UserID, session.ID, user.Role, &now, acr)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Token generation failed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.ReauthenticateResponse{
+		Message:   "Re-authenticated successfully",
+		Token:     token,
+		ExpiresIn: int(config.AccessTokenTTL.Seconds()),
+	})
+}
@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"bank-app/config"
+	"bank-app/models"
+	"bank-app/money"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestRequireStepUp(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("STEP_UP_THRESHOLD", "1000.00")
+	t.Setenv("STEP_UP_THRESHOLD_EUR", "500.00")
+	t.Setenv("STEP_UP_MAX_AGE", "5m")
+
+	recent := time.Now().Add(-time.Minute)
+	stale := time.Now().Add(-10 * time.Minute)
+
+	cases := []struct {
+		name     string
+		amount   money.Amount
+		currency money.Currency
+		authTime *time.Time
+		apiKey   bool
+		want     int
+	}{
+		{"below", money.FromMinor(999_99), money.USD, nil, false, 0},
+		{"at the threshold", money.FromMinor(1000_00), money.USD, nil, false, 0},
+		{"above without auth_time", money.FromMinor(1000_01), money.USD, nil, false, http.StatusUnauthorized},
+		{"above, stale auth_time", money.FromMinor(1000_01), money.USD, &stale, false, http.StatusUnauthorized},
+		{"above, recent auth_time", money.FromMinor(1000_01), money.USD, &recent, false, 0},
+		{"API key below", money.FromMinor(10_00), money.USD, nil, true, 0},
+		{"API key above", money.FromMinor(1000_01), money.USD, &recent, true, http.StatusForbidden},
+		{"own currency threshold", money.FromMinor(500_01), money.EUR, nil, false, http.StatusUnauthorized},
+		{"within own currency threshold", money.FromMinor(500_00), money.EUR, nil, false, 0},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/", nil)
+		if tc.authTime != nil {
+			c.Set("authTime", *tc.authTime)
+		}
+		if tc.apiKey {
+			c.Set("apiKeyID", uint(1))
+		}
+
+		ok := requireStepUp(c, tc.amount, tc.currency)
+		if ok != (tc.want == 0) {
+			t.Errorf("%s: allowed %v, want %v", tc.name, ok, tc.want == 0)
+			continue
+		}
+		if tc.want != 0 && w.Code != tc.want {
+			t.Errorf("%s: status %d, want %d", tc.name, w.Code, tc.want)
+		}
+		if tc.want == http.StatusUnauthorized && !strings.Contains(w.Header().Get("WWW-Authenticate"), "insufficient_user_authentication") {
+			t.Errorf("%s: WWW-Authenticate = %q", tc.name, w.Header().Get("WWW-Authenticate"))
+		}
+	}
+}
+
+// Fees count towards the threshold: an amount just under it plus its fee
+// still needs a recent authentication.
+func TestStepUpCountsFees(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("STEP_UP_THRESHOLD", "1000.00")
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/", nil)
+
+	account := models.Account{Balance: money.FromMinor(5000_00), Currency: money.USD}
+	charges := []models.FeeCharge{
+		{Kind: "transfer", Amount: money.FromMinor(2_00)},
+		{Kind: "excess_withdrawal", Amount: money.FromMinor(50_00), Waived: true},
+	}
+	debit, ok := coversAmountAndFees(c, account, money.FromMinor(999_00), charges)
+	if !ok || debit != money.FromMinor(1001_00) {
+		t.Fatalf("debit = %s, %v; want 1001.00", debit, ok)
+	}
+	if requireStepUp(c, debit, account.Currency) {
+		t.Error("amount and fees over the threshold allowed without step-up")
+	}
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("status %d, want 401", w.Code)
+	}
+}
+
+func TestReauthenticate(t *testing.T) {
+	openTestDB(t)
+	gin.SetMode(gin.TestMode)
+	t.Setenv("JWT_SECRET", "reauth-test-secret")
+	t.Setenv("LOGIN_BASE_BACKOFF", "1ns")
+	t.Setenv("LOGIN_MAX_BACKOFF", "1ns")
+	if err := config.LoadJWTKeys(); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	hash, err := bcrypt.GenerateFromPassword([]byte("Correct-horse-1"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := models.User{
+		FirstName:       "Reauth",
+		LastName:        "Test",
+		Email:           fmt.Sprintf("reauth-%d@example.com", now.UnixNano()),
+		Password:        string(hash),
+		Role:            "customer",
+		EmailVerifiedAt: &now,
+	}
+	if err := config.DB.Create(&user).Error; err != nil {
+		t.Fatalf("creating user: %v", err)
+	}
+	oldAuth := now.Add(-time.Hour)
+	session := models.Session{UserID: user.ID, AuthTime: &oldAuth, ACR: config.ACRPassword}
+	if err := config.DB.Create(&session).Error; err != nil {
+		t.Fatalf("creating session: %v", err)
+	}
+	remoteAddr := fmt.Sprintf("10.3.%d.%d:40000", now.Nanosecond()>>8&0xff, now.Nanosecond()&0xff)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("correlationID", "reauth-test")
+		c.Set("userID", user.ID)
+		c.Set("sessionID", session.ID)
+	})
+	router.POST("/reauth", Reauthenticate)
+
+	reauth := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/reauth", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := reauth(`{}`); w.Code != http.StatusBadRequest {
+		t.Errorf("no password: got %d, want 400", w.Code)
+	}
+	if w := reauth(`{"password":"wrong-password"}`); w.Code != http.StatusUnauthorized {
+		t.Errorf("wrong password: got %d, want 401", w.Code)
+	}
+	if err := config.DB.First(&session, session.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if session.AuthTime == nil || session.AuthTime.After(oldAuth.Add(time.Second)) {
+		t.Errorf("wrong password moved auth_time to %v", session.AuthTime)
+	}
+
+	if w := reauth(`{"password":"Correct-horse-1"}`); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"token"`) {
+		t.Fatalf("right password: got %d %s, want 200 with a token", w.Code, w.Body.String())
+	}
+	if err := config.DB.First(&session, session.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if session.AuthTime == nil || time.Since(*session.AuthTime) > time.Minute || session.ACR != config.ACRPassword {
+		t.Errorf("session auth_time %v, acr %q; want just now with a password", session.AuthTime, session.ACR)
+	}
+	// The right password clears the earlier failure
+	var throttle models.LoginThrottle
+	if err := config.DB.Where("scope = ? AND subject = ?", throttleAccount, normalizeEmail(user.Email)).First(&throttle).Error; err == nil {
+		t.Errorf("throttle still has %d failures after re-authenticating", throttle.Failures)
+	}
+
+	// Users with a second factor must use it
+	if err := config.DB.Model(&user).Update("totp_enabled", true).Error; err != nil {
+		t.Fatal(err)
+	}
+	if w := reauth(`{"password":"Correct-horse-1"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("password without a code for a TOTP user: got %d, want 400", w.Code)
+	}
+}
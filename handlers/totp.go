@@ -0,0 +1,271 @@
+package handlers
+
+import (
+	"bank-app/config"
+	"bank-app/models"
+	"bank-app/totp"
+	"crypto/rand"
+	"encoding/base32"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+const recoveryCodeCount = 10
+
+// @Summary      Start TOTP enrollment
+// @Description  Generates a new TOTP secret for the current user. It only takes effect once confirmed with a code from the authenticator app.
+// @Tags         Auth
+// @Produce      json
+// @Success      200  {object}  models.TOTPEnrollResponse
+// @Failure      404  {object}  models.ErrorResponse
+// @Failure      409  {object}  models.ErrorResponse
+// @Failure      500  {object}  models.ErrorResponse
+// @Security     BearerAuth
+// @Router       /users/me/totp/enroll [post]
+func EnrollTOTP(c *gin.Context) {
+	var user models.User
+	if err := config.DB.First(&user, c.MustGet("userID").(uint)).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Message: "User not found"})
+		return
+	}
+	if user.TOTPEnabled {
+		c.JSON(http.StatusConflict, models.ErrorResponse{Message: "Two-factor authentication is already enabled"})
+		return
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to generate secret"})
+		return
+	}
+	if err := config.DB.Model(&user).Update("totp_pending_secret", secret).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to start enrollment"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.TOTPEnrollResponse{
+		Secret:     secret,
+		OTPAuthURI: totp.URI(totpIssuer(), user.Email, secret),
+	})
+}
+
+// @Summary      Confirm TOTP enrollment
+// @Description  Enables two-factor login once the user proves their authenticator produces valid codes. Returns recovery codes, which are shown only this once.
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        request  body      models.TOTPCodeRequest  true  "Code from the authenticator app"
+// @Success      200      {object}  models.RecoveryCodesResponse
+// @Failure      400      {object}  models.ErrorResponse
+// @Failure      404      {object}  models.ErrorResponse
+// @Failure      409      {object}  models.ErrorResponse
+// @Failure      500      {object}  models.ErrorResponse
+// @Security     BearerAuth
+// @Router       /users/me/totp/confirm [post]
+func ConfirmTOTP(c *gin.Context) {
+	var req models.TOTPCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	tx := config.DB.Begin()
+	var user models.User
+	if err := tx.Set("gorm:query_option", "FOR UPDATE").First(&user, c.MustGet("userID").(uint)).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Message: "User not found"})
+		return
+	}
+	if user.TOTPEnabled {
+		tx.Rollback()
+		c.JSON(http.StatusConflict, models.ErrorResponse{Message: "Two-factor authentication is already enabled"})
+		return
+	}
+	if user.TOTPPendingSecret == "" {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Start enrollment first"})
+		return
+	}
+
+	step, ok := totp.Validate(user.TOTPPendingSecret, req.Code, time.Now(), 0)
+	if !ok {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Invalid code"})
+		return
+	}
+
+	if err := tx.Model(&user).Updates(map[string]interface{}{
+		"totp_secret":         user.TOTPPendingSecret,
+		"totp_pending_secret": "",
+		"totp_enabled":        true,
+		"totp_last_step":      step,
+	}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to enable two-factor authentication"})
+		return
+	}
+
+	codes, err := replaceRecoveryCodes(tx, user.ID)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to generate recovery codes"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to enable two-factor authentication"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.RecoveryCodesResponse{
+		Message:       "Two-factor authentication enabled",
+		RecoveryCodes: codes,
+	})
+}
+
+// @Summary      Complete a two-factor login
+// @Description  Exchanges the challenge token returned by /login, together with a TOTP code or an unused recovery code, for access and refresh tokens.
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        request  body      models.TOTPLoginRequest  true  "Challenge token and code"
+// @Success      200      {object}  models.LoginResponse
+// @Failure      400      {object}  models.ErrorResponse
+// @Failure      401      {object}  models.ErrorResponse
+// @Failure      500      {object}  models.ErrorResponse
+// @Router       /login/totp [post]
+func LoginTOTP(c *gin.Context) {
+	var req models.TOTPLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: err.Error()})
+		return
+	}
+	if (req.Code == "") == (req.RecoveryCode == "") {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Provide either code or recovery_code"})
+		return
+	}
+
+	userID, err := config.ParseMFAChallenge(req.ChallengeToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Message: "Invalid or expired challenge"})
+		return
+	}
+
+	// The user row is locked so two requests cannot both accept the same code
+	tx := config.DB.Begin()
+	var user models.User
+	if err := tx.Set("gorm:query_option", "FOR UPDATE").First(&user, userID).Error; err != nil || !user.TOTPEnabled {
+		tx.Rollback()
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Message: "Invalid or expired challenge"})
+		return
+	}
+
+	// Codes count towards the same lockout as passwords
+	if !countLoginAttempt(c, user.Email, &user) {
+		tx.Rollback()
+		return
+	}
+
+	if req.Code != "" {
+		ok, err := verifyTOTP(tx, &user, req.Code)
+		if err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to verify code"})
+			return
+		}
+		if !ok {
+			tx.Rollback()
+			c.JSON(http.StatusUnauthorized, models.ErrorResponse{Message: "Invalid code"})
+			return
+		}
+	} else {
+		now := time.Now()
+		result := tx.Model(&models.RecoveryCode{}).
+			Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.ID, config.HashToken(normalizeRecoveryCode(req.RecoveryCode))).
+			Update("used_at", &now)
+		if result.Error != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to verify code"})
+			return
+		}
+		if result.RowsAffected == 0 {
+			tx.Rollback()
+			c.JSON(http.StatusUnauthorized, models.ErrorResponse{Message: "Invalid code"})
+			return
+		}
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to verify code"})
+		return
+	}
+
+	loginSucceeded(c, user.Email)
+
+	tokens, err := startSession(user, config.ACRMFA)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Token generation failed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.LoginResponse{
+		Message:      "Login successful",
+		Token:        tokens.Token,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
+		User:         userResponse(user),
+	})
+}
+
+// verifyTOTP checks code against user's secret and records the step it
+// matched so the code cannot be used again. user must be locked by tx.
+func verifyTOTP(tx *gorm.DB, user *models.User, code string) (bool, error) {
+	step, ok := totp.Validate(user.TOTPSecret, code, time.Now(), user.TOTPLastStep)
+	if !ok {
+		return false, nil
+	}
+	if err := tx.Model(user).Update("totp_last_step", step).Error; err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// replaceRecoveryCodes discards a user's existing recovery codes and stores
+// hashes of a fresh set, returning the codes in plain text.
+func replaceRecoveryCodes(tx *gorm.DB, userID uint) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		code := strings.ToLower(base32.StdEncoding.EncodeToString(b))
+		code = code[:4] + "-" + code[4:]
+		if err := tx.Create(&models.RecoveryCode{
+			UserID:   userID,
+			CodeHash: config.HashToken(normalizeRecoveryCode(code)),
+		}).Error; err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+}
+
+func totpIssuer() string {
+	if issuer := os.Getenv("TOTP_ISSUER"); issuer != "" {
+		return issuer
+	}
+	return "Bank API"
+}
@@ -1,9 +1,12 @@
 package handlers
 
 import (
+	"bank-app/authz"
 	"bank-app/config"
 	"bank-app/models"
+	"bank-app/money"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jinzhu/gorm"
@@ -16,17 +19,30 @@ func GetAllTransactionsSummary(c *gin.Context) {
 		return
 	}
 
+	// Amounts in different currencies cannot be added, so totals are kept
+	// per currency
 	summary := make(map[string]interface{})
-	totalAmount := 0.0
-	byType := map[string]float64{}
+	totalAmount := map[money.Currency]money.Amount{}
+	byType := map[string]map[money.Currency]money.Amount{}
 	byStatus := map[string]int{}
 	perDay := map[string]int{}
 
 	for _, tx := range transactions {
-		totalAmount += tx.Amount
+		var err error
+		if totalAmount[tx.Currency], err = totalAmount[tx.Currency].Add(tx.Amount); err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Transaction totals overflowed"})
+			return
+		}
 
 		// By type
-		byType[tx.TransactionType] += tx.Amount
+		if byType[tx.TransactionType] == nil {
+			byType[tx.TransactionType] = map[money.Currency]money.Amount{}
+		}
+		totals := byType[tx.TransactionType]
+		if totals[tx.Currency], err = totals[tx.Currency].Add(tx.Amount); err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Transaction totals overflowed"})
+			return
+		}
 
 		// By status
 		byStatus[tx.Status]++
@@ -52,6 +68,7 @@ func GetAllTransactionsSummary(c *gin.Context) {
 // @Produce      json
 // @Param        id   path      string             true  "Transaction ID"
 // @Success      200  {object}  models.Transaction
+// @Failure      403  {object}  models.ErrorResponse
 // @Failure      404  {object}  models.ErrorResponse
 // @Failure      500  {object}  models.ErrorResponse
 // @Security     BearerAuth
@@ -67,6 +84,17 @@ func GetTransactionByID(c *gin.Context) {
 		}
 		return
 	}
+
+	// Transactions are visible to whoever may see the account they belong to
+	var account models.Account
+	if err := config.DB.First(&account, transaction.AccountID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to retrieve transaction"})
+		return
+	}
+	if !authz.CanViewTransaction(principal(c), account) {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{Message: "Access denied"})
+		return
+	}
 	c.JSON(http.StatusOK, transaction)
 }
 
@@ -76,11 +104,23 @@ func GetTransactionByID(c *gin.Context) {
 // @Param        id   path      string  true  "User ID"
 // @Produce      json
 // @Success      200  {array}   models.Transaction
+// @Failure      403  {object}  models.ErrorResponse  "Access denied"
 // @Failure      404  {object}  models.ErrorResponse  "User not found"
 // @Failure      500  {object}  models.ErrorResponse  "Failed to fetch transactions"
+// @Security     BearerAuth
 // @Router       /users/{id}/transactions [get]
 func GetTransactionsByUserID(c *gin.Context) {
-	userID := c.Param("id")
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Message: "User not found"})
+		return
+	}
+
+	if !authz.CanViewUser(principal(c), uint(userID)) {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{Message: "Access denied"})
+		return
+	}
+
 	var accounts []models.Account
 
 	// Fetch all accounts for the given user
@@ -114,8 +154,10 @@ func getAccountIDs(accounts []models.Account) []uint {
 // @Param        account_no  path      string  true  "Account Number"
 // @Produce      json
 // @Success      200  {array}   models.Transaction
+// @Failure      403  {object}  models.ErrorResponse  "Access denied"
 // @Failure      404  {object}  models.ErrorResponse  "Account not found"
 // @Failure      500  {object}  models.ErrorResponse  "Failed to fetch transactions"
+// @Security     BearerAuth
 // @Router       /accounts/{account_no}/transactions [get]
 func GetTransactionsByAccountNo(c *gin.Context) {
 	accountNo := c.Param("account_no")
@@ -127,6 +169,11 @@ func GetTransactionsByAccountNo(c *gin.Context) {
 		return
 	}
 
+	if !authz.CanViewAccount(principal(c), account) {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{Message: "Access denied"})
+		return
+	}
+
 	// Fetch transactions for the given account
 	var transactions []models.Transaction
 	if err := config.DB.Where("account_id = ?", account.ID).Find(&transactions).Error; err != nil {
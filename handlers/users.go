@@ -1,21 +1,24 @@
 package handlers
 
 import (
+	"bank-app/authz"
 	"bank-app/config"
 	"bank-app/models"
-	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jinzhu/gorm"
 )
 
 func GetUserByID(c *gin.Context) {
-	paramID := c.Param("id")
-
-	userID := c.MustGet("userID").(uint)
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
 
-	if fmt.Sprintf("%d", userID) != paramID {
+	if !authz.CanViewUser(principal(c), uint(userID)) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
 		return
 	}
@@ -30,5 +33,54 @@ func GetUserByID(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, user)
+	c.JSON(http.StatusOK, models.UserDetailResponse{UserResponse: userResponse(user), Accounts: user.Accounts})
+}
+
+// @Summary      Change a user's role
+// @Description  Assigns customer, teller, admin or auditor. The user's next access token carries the new role.
+// @Tags         Users
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string              true  "User ID"
+// @Param        request  body      models.RoleRequest  true  "New role"
+// @Success      200      {object}  models.UserResponse
+// @Failure      400      {object}  models.ErrorResponse
+// @Failure      404      {object}  models.ErrorResponse
+// @Failure      500      {object}  models.ErrorResponse
+// @Security     BearerAuth
+// @Router       /admin/users/{id}/role [put]
+func UpdateUserRole(c *gin.Context) {
+	var req models.RoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil || !authz.ValidRole(req.Role) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Invalid role"})
+		return
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, c.Param("id")).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{Message: "User not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to retrieve user"})
+		}
+		return
+	}
+
+	if err := config.DB.Model(&user).Update("role", req.Role).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to update role"})
+		return
+	}
+
+	c.JSON(http.StatusOK, userResponse(user))
+}
+
+func userResponse(user models.User) models.UserResponse {
+	return models.UserResponse{
+		ID:            user.ID,
+		FirstName:     user.FirstName,
+		LastName:      user.LastName,
+		Email:         user.Email,
+		Role:          user.Role,
+		EmailVerified: user.EmailVerifiedAt != nil,
+	}
 }
@@ -0,0 +1,304 @@
+package handlers
+
+import (
+	"bank-app/config"
+	"bank-app/events"
+	"bank-app/models"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	tokenPurposeVerifyEmail   = "verify_email"
+	tokenPurposeResetPassword = "reset_password"
+)
+
+var errInvalidUserToken = errors.New("invalid or expired token")
+
+// issueUserToken replaces any outstanding token of the same purpose with a
+// new one, so only the most recently emailed link works.
+func issueUserToken(tx *gorm.DB, userID uint, purpose string, ttl time.Duration) (string, time.Time, error) {
+	if err := tx.Unscoped().
+		Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+		Delete(&models.UserToken{}).Error; err != nil {
+		return "", time.Time{}, err
+	}
+
+	token, hash, err := config.GenerateToken()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	expiresAt := time.Now().Add(ttl)
+	if err := tx.Create(&models.UserToken{
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: hash,
+		ExpiresAt: expiresAt,
+	}).Error; err != nil {
+		return "", time.Time{}, err
+	}
+	return token, expiresAt, nil
+}
+
+// consumeUserToken marks a token used and returns it, or errInvalidUserToken
+// if it is unknown, already used or expired.
+func consumeUserToken(tx *gorm.DB, token, purpose string) (*models.UserToken, error) {
+	var stored models.UserToken
+	err := tx.Set("gorm:query_option", "FOR UPDATE").
+		Where("token_hash = ? AND purpose = ?", config.HashToken(token), purpose).
+		First(&stored).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return nil, errInvalidUserToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if stored.UsedAt != nil || stored.ExpiresAt.Before(time.Now()) {
+		return nil, errInvalidUserToken
+	}
+
+	now := time.Now()
+	if err := tx.Model(&stored).Update("used_at", &now).Error; err != nil {
+		return nil, err
+	}
+	return &stored, nil
+}
+
+// sendEmailVerification issues a verification token for user and queues the
+// email that delivers it.
+func sendEmailVerification(c *gin.Context, tx *gorm.DB, user models.User) error {
+	token, expiresAt, err := issueUserToken(tx, user.ID, tokenPurposeVerifyEmail, config.EmailVerificationTTL())
+	if err != nil {
+		return err
+	}
+	return enqueueEvent(c, tx, &events.EmailVerificationRequested{
+		UserID:    user.ID,
+		FirstName: user.FirstName,
+		Link:      config.AppURL("/verify-email?token=" + url.QueryEscape(token)),
+		ExpiresAt: expiresAt,
+		ToEmail:   user.Email,
+	})
+}
+
+// @Summary      Verify an email address
+// @Description  Consumes the token from a verification email and marks the user's address verified
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        request  body      models.TokenRequest  true  "Verification token"
+// @Success      200      {object}  map[string]string
+// @Failure      400      {object}  models.ErrorResponse
+// @Failure      500      {object}  models.ErrorResponse
+// @Router       /email/verify [post]
+func VerifyEmail(c *gin.Context) {
+	var req models.TokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	tx := config.DB.Begin()
+	stored, err := consumeUserToken(tx, req.Token, tokenPurposeVerifyEmail)
+	if err != nil {
+		tx.Rollback()
+		if err == errInvalidUserToken {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Invalid or expired token"})
+		} else {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to verify email"})
+		}
+		return
+	}
+
+	now := time.Now()
+	if err := tx.Model(&models.User{}).
+		Where("id = ? AND email_verified_at IS NULL", stored.UserID).
+		Update("email_verified_at", &now).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to verify email"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to verify email"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email verified successfully"})
+}
+
+// @Summary      Resend the verification email
+// @Description  Sends a new verification link to the current user, invalidating any earlier one. Requests are limited per address and per client IP address.
+// @Tags         Auth
+// @Produce      json
+// @Success      202  {object}  map[string]string
+// @Failure      404  {object}  models.ErrorResponse
+// @Failure      409  {object}  models.ErrorResponse
+// @Failure      429  {object}  models.ErrorResponse
+// @Failure      500  {object}  models.ErrorResponse
+// @Security     BearerAuth
+// @Router       /email/verification [post]
+func ResendEmailVerification(c *gin.Context) {
+	var user models.User
+	if err := config.DB.First(&user, c.MustGet("userID").(uint)).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Message: "User not found"})
+		return
+	}
+	if user.EmailVerifiedAt != nil {
+		c.JSON(http.StatusConflict, models.ErrorResponse{Message: "Email is already verified"})
+		return
+	}
+	if !countEmailRequest(c, user.Email) {
+		return
+	}
+
+	tx := config.DB.Begin()
+	if err := sendEmailVerification(c, tx, user); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to send verification email"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to send verification email"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Verification email sent"})
+}
+
+// @Summary      Request a password reset
+// @Description  Emails a password reset link if an account exists for the address. The response is the same either way, so it cannot be used to discover accounts. Requests are limited per address and per client IP address.
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        request  body      models.ForgotPasswordRequest  true  "Account email"
+// @Success      202      {object}  map[string]string
+// @Failure      400      {object}  models.ErrorResponse
+// @Failure      429      {object}  models.ErrorResponse
+// @Failure      500      {object}  models.ErrorResponse
+// @Router       /password/forgot [post]
+func ForgotPassword(c *gin.Context) {
+	var req models.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	if !countEmailRequest(c, req.Email) {
+		return
+	}
+
+	accepted := gin.H{"message": "If an account exists for this email, a reset link has been sent"}
+
+	var user models.User
+	if err := config.DB.Where("email = ?", req.Email).First(&user).Error; err != nil {
+		c.JSON(http.StatusAccepted, accepted)
+		return
+	}
+
+	tx := config.DB.Begin()
+	token, expiresAt, err := issueUserToken(tx, user.ID, tokenPurposeResetPassword, config.PasswordResetTTL())
+	if err == nil {
+		err = enqueueEvent(c, tx, &events.PasswordResetRequested{
+			UserID:    user.ID,
+			FirstName: user.FirstName,
+			Link:      config.AppURL("/reset-password?token=" + url.QueryEscape(token)),
+			ExpiresAt: expiresAt,
+			ToEmail:   user.Email,
+		})
+	}
+	if err == nil {
+		err = tx.Commit().Error
+	} else {
+		tx.Rollback()
+	}
+	if err != nil {
+		log.Printf("Failed to issue password reset for user %d: %v\n", user.ID, err)
+	}
+
+	c.JSON(http.StatusAccepted, accepted)
+}
+
+// @Summary      Reset a password
+// @Description  Sets a new password using the token from a reset email. The password must satisfy the password policy and differ from recent ones. All of the user's sessions are logged out and API keys revoked.
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        request  body      models.ResetPasswordRequest  true  "Reset token and new password"
+// @Success      200      {object}  map[string]string
+// @Failure      400      {object}  models.ErrorResponse
+// @Failure      500      {object}  models.ErrorResponse
+// @Router       /password/reset [post]
+func ResetPassword(c *gin.Context) {
+	var req models.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	tx := config.DB.Begin()
+	stored, err := consumeUserToken(tx, req.Token, tokenPurposeResetPassword)
+	if err != nil {
+		tx.Rollback()
+		if err == errInvalidUserToken {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Invalid or expired token"})
+		} else {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to reset password"})
+		}
+		return
+	}
+
+	var user models.User
+	if err := tx.Set("gorm:query_option", "FOR UPDATE").First(&user, stored.UserID).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Invalid or expired token"})
+		return
+	}
+
+	// A rejected password rolls back, leaving the token usable for another try
+	if err := setPassword(tx, &user, req.NewPassword); err != nil {
+		tx.Rollback()
+		passwordError(c, err)
+		return
+	}
+
+	// Receiving the email proves the address too
+	if user.EmailVerifiedAt == nil {
+		if err := tx.Model(&user).Update("email_verified_at", time.Now()).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to reset password"})
+			return
+		}
+	}
+
+	// Whoever knew the old password must not stay logged in or keep an API
+	// key minted with it, and the owner should not stay locked out
+	now := time.Now()
+	if err := tx.Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", user.ID).
+		Updates(map[string]interface{}{"revoked_at": &now, "revoked_reason": "password reset"}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to reset password"})
+		return
+	}
+	if err := revokeAPIKeys(tx, user.ID); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to reset password"})
+		return
+	}
+	if err := clearLoginFailures(tx, user.Email); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to reset password"})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to reset password"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password reset successfully"})
+}
@@ -0,0 +1,245 @@
+package interest
+
+import (
+	"bank-app/catalog"
+	"bank-app/ledger"
+	"bank-app/lifecycle"
+	"bank-app/models"
+	"bank-app/money"
+	"context"
+	"log"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Engine accrues interest daily on every open account's end-of-day balance
+// and pays it in once a month. Each run catches up on any days missed while
+// the service was down. Accounts are locked while they are processed, so
+// several instances can run at once.
+type Engine struct {
+	DB       *gorm.DB
+	Interval time.Duration
+}
+
+func NewEngine(db *gorm.DB) *Engine {
+	return &Engine{DB: db, Interval: time.Hour}
+}
+
+// Run processes accounts until ctx is cancelled.
+func (e *Engine) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := e.RunOnce(time.Now()); err != nil {
+			log.Printf("Interest run failed: %v\n", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce accrues interest for every complete day before now and
+// capitalizes every complete month. A failure on one account is logged and
+// does not stop the others.
+func (e *Engine) RunOnce(now time.Time) error {
+	var ids []uint
+	if err := e.DB.Model(&models.Account{}).Where("status <> ?", lifecycle.Closed).Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+
+	today := startOfDay(now)
+	for _, id := range ids {
+		if err := e.processAccount(id, today); err != nil {
+			log.Printf("Interest run failed for account %d: %v\n", id, err)
+		}
+	}
+	return nil
+}
+
+func (e *Engine) processAccount(id uint, today time.Time) error {
+	tx := e.DB.Begin()
+	var account models.Account
+	if err := tx.Set("gorm:query_option", "FOR UPDATE").First(&account, id).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if account.Status == lifecycle.Closed {
+		tx.Rollback()
+		return nil
+	}
+
+	if err := accrue(tx, &account, today); err != nil {
+		tx.Rollback()
+		return err
+	}
+	monthStart := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, today.Location())
+	if err := Capitalize(tx, &account, monthStart); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
+
+// Settle accrues and pays in all interest owed to a locked account for the
+// days before now, such as when it is closed.
+func Settle(tx *gorm.DB, account *models.Account, now time.Time) error {
+	today := startOfDay(now)
+	if err := accrue(tx, account, today); err != nil {
+		return err
+	}
+	return Capitalize(tx, account, today)
+}
+
+// accrue records the interest for each day from the one after the account's
+// last accrual up to, but not including, today. An account seen for the first
+// time starts from yesterday, or the day it opened if later, so interest is
+// never back-paid for days before the engine ran.
+func accrue(tx *gorm.DB, account *models.Account, today time.Time) error {
+	yesterday := today.AddDate(0, 0, -1)
+	start := startOfDay(account.CreatedAt)
+	if account.InterestAccruedThrough != nil {
+		start = startOfDay(*account.InterestAccruedThrough).AddDate(0, 0, 1)
+	} else if start.Before(yesterday) {
+		start = yesterday
+	}
+	if !start.Before(today) {
+		return nil
+	}
+
+	// Retired products keep paying the accounts already on them
+	var product models.AccountProduct
+	err := tx.Unscoped().Preload("InterestTiers").Where("code = ?", account.AccountType).First(&product).Error
+	if err != nil && !gorm.IsRecordNotFoundError(err) {
+		return err
+	}
+
+	// Tier balances are in the product's currency, so they mean nothing for
+	// an account in another
+	if Bearing(product.InterestTiers) && catalog.AppliesTo(product, account.Currency) {
+		customer, err := ledger.CustomerAccount(tx, account)
+		if err != nil {
+			return err
+		}
+		for day := start; day.Before(today); day = day.AddDate(0, 0, 1) {
+			balance, err := ledger.BalanceAt(tx, customer, day.AddDate(0, 0, 1))
+			if err != nil {
+				return err
+			}
+			rate := RateFor(product.InterestTiers, balance)
+			num, den := DayFraction(product.InterestDayCount, day)
+			if err := tx.Create(&models.InterestAccrual{
+				AccountID:   account.ID,
+				AccrualDate: day,
+				Balance:     balance,
+				RateBps:     rate,
+				DayCount:    product.InterestDayCount,
+				DayFraction: FormatFraction(num, den),
+				Accrued:     Accrue(balance, rate, num, den),
+			}).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Model(account).Update("interest_accrued_through", &yesterday).Error
+}
+
+// Capitalize pays interest accrued on days before `before` into a locked
+// account, one payment per calendar month. Each month's total is rounded to
+// minor units; the fraction of a cent left over is not carried forward.
+func Capitalize(tx *gorm.DB, account *models.Account, before time.Time) error {
+	var accruals []models.InterestAccrual
+	if err := tx.Where("account_id = ? AND capitalization_id IS NULL AND accrual_date < ?", account.ID, before).
+		Order("accrual_date").
+		Find(&accruals).Error; err != nil {
+		return err
+	}
+
+	for len(accruals) > 0 {
+		first := accruals[0].AccrualDate
+		n := 1
+		for n < len(accruals) && sameMonth(accruals[n].AccrualDate, first) {
+			n++
+		}
+		if err := pay(tx, account, accruals[:n]); err != nil {
+			return err
+		}
+		accruals = accruals[n:]
+	}
+	return nil
+}
+
+func pay(tx *gorm.DB, account *models.Account, accruals []models.InterestAccrual) error {
+	var total money.Micros
+	ids := make([]uint, len(accruals))
+	for i, accrual := range accruals {
+		total += accrual.Accrued
+		ids[i] = accrual.ID
+	}
+
+	capitalization := models.InterestCapitalization{
+		AccountID:   account.ID,
+		PeriodStart: accruals[0].AccrualDate,
+		PeriodEnd:   accruals[len(accruals)-1].AccrualDate,
+		Accrued:     total,
+		Amount:      total.Round(),
+	}
+
+	if capitalization.Amount.IsPositive() {
+		expense, err := ledger.SystemAccount(tx, ledger.Interest, account.Currency)
+		if err != nil {
+			return err
+		}
+		customer, err := ledger.CustomerAccount(tx, account)
+		if err != nil {
+			return err
+		}
+		journal, err := ledger.Post(tx, ledger.Entry{
+			Kind:        "interest",
+			Description: "Interest for " + capitalization.PeriodStart.Format("January 2006") + " on " + account.AccountNo,
+			Lines: []ledger.Line{
+				ledger.DebitLine(expense, capitalization.Amount),
+				ledger.CreditLine(customer, capitalization.Amount),
+			},
+		})
+		if err != nil {
+			return err
+		}
+
+		transaction := models.Transaction{
+			TransactionType: "interest",
+			Amount:          capitalization.Amount,
+			Currency:        account.Currency,
+			AccountID:       account.ID,
+			Status:          "success",
+			JournalEntryID:  &journal.ID,
+			TransactionDate: time.Now(),
+		}
+		if err := tx.Create(&transaction).Error; err != nil {
+			return err
+		}
+		capitalization.TransactionID = &transaction.ID
+	}
+
+	if err := tx.Create(&capitalization).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.InterestAccrual{}).
+		Where("id IN (?)", ids).
+		Update("capitalization_id", capitalization.ID).Error
+}
+
+func startOfDay(t time.Time) time.Time {
+	t = t.Local()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+}
+
+func sameMonth(a, b time.Time) bool {
+	return a.Year() == b.Year() && a.Month() == b.Month()
+}
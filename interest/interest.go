@@ -0,0 +1,84 @@
+package interest
+
+import (
+	"bank-app/catalog"
+	"bank-app/models"
+	"bank-app/money"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// DayFraction returns the fraction of a year, as num/den, that the day
+// starting at day counts for under convention.
+func DayFraction(convention string, day time.Time) (num, den int64) {
+	if convention == catalog.DayCount30360 {
+		return days360(day, day.AddDate(0, 0, 1)), 360
+	}
+	return 1, 365
+}
+
+// days360 counts the days between two dates as if every month had 30, so a
+// month always totals 30 however long it is. This is the bond basis: a 31st
+// is treated as the 30th.
+func days360(from, to time.Time) int64 {
+	y1, m1, d1 := from.Date()
+	y2, m2, d2 := to.Date()
+	if d1 == 31 {
+		d1 = 30
+	}
+	if d2 == 31 && d1 == 30 {
+		d2 = 30
+	}
+	return int64((y2-y1)*360 + (int(m2)-int(m1))*30 + (d2 - d1))
+}
+
+// RateFor returns the rate of the highest tier balance reaches. The whole
+// balance earns that rate; there is no banding.
+func RateFor(tiers []models.InterestTier, balance money.Amount) int {
+	rate := 0
+	var reached money.Amount = -1
+	for _, tier := range tiers {
+		if balance >= tier.MinBalance && tier.MinBalance > reached {
+			rate, reached = tier.RateBps, tier.MinBalance
+		}
+	}
+	return rate
+}
+
+// Bearing reports whether any tier pays interest.
+func Bearing(tiers []models.InterestTier) bool {
+	for _, tier := range tiers {
+		if tier.RateBps > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Accrue returns the interest on balance at rateBps a year for num/den of a
+// year, rounded half to even in micros.
+func Accrue(balance money.Amount, rateBps int, num, den int64) money.Micros {
+	if !balance.IsPositive() || rateBps <= 0 || num <= 0 {
+		return 0
+	}
+	// Minor units are 10^4 micros and basis points are 10^-4, so they cancel
+	product := new(big.Int).Mul(big.NewInt(balance.Minor()), big.NewInt(int64(rateBps)))
+	product.Mul(product, big.NewInt(num))
+
+	q, r := new(big.Int).QuoRem(product, big.NewInt(den), new(big.Int))
+	switch r.Mul(r, big.NewInt(2)).Cmp(big.NewInt(den)) {
+	case 1:
+		q.Add(q, big.NewInt(1))
+	case 0:
+		if q.Bit(0) == 1 {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	return money.Micros(q.Int64())
+}
+
+// FormatFraction renders a day fraction for the accrual history.
+func FormatFraction(num, den int64) string {
+	return fmt.Sprintf("%d/%d", num, den)
+}
@@ -0,0 +1,132 @@
+package interest
+
+import (
+	"bank-app/catalog"
+	"bank-app/models"
+	"bank-app/money"
+	"testing"
+	"time"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestDays360(t *testing.T) {
+	cases := []struct {
+		from, to time.Time
+		want     int64
+	}{
+		{date(2025, 1, 1), date(2025, 1, 2), 1},
+		{date(2025, 1, 30), date(2025, 1, 31), 0},
+		{date(2025, 1, 31), date(2025, 2, 1), 1},
+		{date(2025, 1, 29), date(2025, 1, 31), 2},
+		{date(2025, 2, 27), date(2025, 2, 28), 1},
+		{date(2025, 2, 28), date(2025, 3, 1), 3},
+		{date(2024, 2, 28), date(2024, 2, 29), 1},
+		{date(2024, 2, 29), date(2024, 3, 1), 2},
+		{date(2025, 12, 31), date(2026, 1, 1), 1},
+		{date(2025, 1, 1), date(2025, 2, 1), 30},
+		{date(2025, 1, 31), date(2025, 3, 31), 60},
+		{date(2025, 1, 1), date(2026, 1, 1), 360},
+	}
+	for _, tc := range cases {
+		if got := days360(tc.from, tc.to); got != tc.want {
+			t.Errorf("days360(%s, %s) = %d, want %d", tc.from.Format(time.DateOnly), tc.to.Format(time.DateOnly), got, tc.want)
+		}
+	}
+}
+
+// Daily fractions must add up to the month's share of the year, so a month
+// earns the same under 30/360 however many days it has.
+func TestDayFractionsTotalAMonth(t *testing.T) {
+	months := []time.Time{
+		date(2025, 1, 1),
+		date(2025, 2, 1),
+		date(2024, 2, 1),
+		date(2025, 4, 1),
+		date(2025, 12, 1),
+	}
+	for _, start := range months {
+		var total int64
+		for day := start; day.Month() == start.Month(); day = day.AddDate(0, 0, 1) {
+			num, den := DayFraction(catalog.DayCount30360, day)
+			if den != 360 {
+				t.Fatalf("30/360 denominator = %d", den)
+			}
+			total += num
+		}
+		if total != 30 {
+			t.Errorf("30/360 days in %s = %d, want 30", start.Format("2006-01"), total)
+		}
+	}
+
+	num, den := DayFraction(catalog.DayCountActual365, date(2024, 2, 29))
+	if num != 1 || den != 365 {
+		t.Errorf("ACT/365 fraction = %d/%d, want 1/365", num, den)
+	}
+}
+
+func TestAccrue(t *testing.T) {
+	cases := []struct {
+		name     string
+		balance  money.Amount
+		rateBps  int
+		num, den int64
+		want     money.Micros
+	}{
+		{"exact", money.FromMinor(100000), 365, 1, 365, 100000},
+		{"a year at 1%", money.FromMinor(100000), 100, 365, 365, 10000000},
+		{"below half rounds down", money.FromMinor(1), 179, 1, 360, 0},
+		{"above half rounds up", money.FromMinor(1), 181, 1, 360, 1},
+		{"half rounds to even, down", money.FromMinor(36), 5, 1, 360, 0},
+		{"half rounds to even, up", money.FromMinor(180), 3, 1, 360, 2},
+		{"half rounds to even, down from two", money.FromMinor(90), 10, 1, 360, 2},
+		{"zero days", money.FromMinor(100000), 100, 0, 360, 0},
+		{"zero rate", money.FromMinor(100000), 0, 1, 365, 0},
+		{"zero balance", 0, 100, 1, 365, 0},
+		{"overdrawn", money.FromMinor(-100000), 100, 1, 365, 0},
+	}
+	for _, tc := range cases {
+		if got := Accrue(tc.balance, tc.rateBps, tc.num, tc.den); got != tc.want {
+			t.Errorf("%s: Accrue(%s, %d, %d/%d) = %d, want %d", tc.name, tc.balance, tc.rateBps, tc.num, tc.den, got, tc.want)
+		}
+	}
+}
+
+func TestRateFor(t *testing.T) {
+	// Deliberately out of order
+	tiers := []models.InterestTier{
+		{MinBalance: money.FromMinor(500000), RateBps: 200},
+		{MinBalance: 0, RateBps: 100},
+		{MinBalance: money.FromMinor(100000), RateBps: 150},
+	}
+	cases := []struct {
+		balance money.Amount
+		want    int
+	}{
+		{0, 100},
+		{money.FromMinor(99999), 100},
+		{money.FromMinor(100000), 150},
+		{money.FromMinor(499999), 150},
+		{money.FromMinor(500000), 200},
+		{money.FromMinor(10000000), 200},
+		{money.FromMinor(-1), 0},
+	}
+	for _, tc := range cases {
+		if got := RateFor(tiers, tc.balance); got != tc.want {
+			t.Errorf("RateFor(%s) = %d, want %d", tc.balance, got, tc.want)
+		}
+	}
+
+	minimum := []models.InterestTier{{MinBalance: money.FromMinor(1000), RateBps: 50}}
+	if got := RateFor(minimum, money.FromMinor(999)); got != 0 {
+		t.Errorf("below the lowest tier: got %d, want 0", got)
+	}
+	if got := RateFor(minimum, money.FromMinor(1000)); got != 50 {
+		t.Errorf("at the lowest tier: got %d, want 50", got)
+	}
+	if got := RateFor(nil, money.FromMinor(1000)); got != 0 {
+		t.Errorf("no tiers: got %d, want 0", got)
+	}
+}
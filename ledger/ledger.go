@@ -0,0 +1,329 @@
+package ledger
+
+import (
+	"bank-app/models"
+	"bank-app/money"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	Debit  = "debit"
+	Credit = "credit"
+)
+
+// Ledger account kinds. Assets and expenses carry debit balances; liabilities
+// (customer deposits) and income carry credit balances.
+const (
+	KindAsset     = "asset"
+	KindLiability = "liability"
+	KindIncome    = "income"
+	KindExpense   = "expense"
+)
+
+// System account codes. Each is kept per currency, e.g. "cash:USD".
+const (
+	Cash     = "cash"
+	Clearing = "clearing"
+	Fees     = "fees"
+	Suspense = "suspense"
+	// FX is the currency position. Conversions credit it in the currency
+	// bought from the customer and debit it in the currency sold, so each
+	// currency still balances on its own
+	FX = "fx"
+	// Interest paid to customers
+	Interest = "interest"
+)
+
+var systemKinds = map[string]string{
+	Cash:     KindAsset,
+	Clearing: KindAsset,
+	Fees:     KindIncome,
+	Suspense: KindLiability,
+	FX:       KindLiability,
+	Interest: KindExpense,
+}
+
+var (
+	ErrUnbalanced     = errors.New("ledger: journal entry does not balance")
+	ErrTooFewLines    = errors.New("ledger: journal entry needs at least two lines")
+	ErrInvalidLine    = errors.New("ledger: posting amount must be positive")
+	ErrUnknownAccount = errors.New("ledger: unknown system account")
+)
+
+// Line is one side of a journal entry before it is posted.
+type Line struct {
+	Account   *models.LedgerAccount
+	Direction string
+	Amount    money.Amount
+}
+
+func DebitLine(account *models.LedgerAccount, amount money.Amount) Line {
+	return Line{Account: account, Direction: Debit, Amount: amount}
+}
+
+func CreditLine(account *models.LedgerAccount, amount money.Amount) Line {
+	return Line{Account: account, Direction: Credit, Amount: amount}
+}
+
+type Entry struct {
+	Kind        string
+	Description string
+	Lines       []Line
+}
+
+// SystemAccount returns the system ledger account for code and currency,
+// creating it on first use.
+func SystemAccount(tx *gorm.DB, code string, currency money.Currency) (*models.LedgerAccount, error) {
+	kind, ok := systemKinds[code]
+	if !ok {
+		return nil, ErrUnknownAccount
+	}
+
+	account := models.LedgerAccount{}
+	err := tx.Where(models.LedgerAccount{Code: fmt.Sprintf("%s:%s", code, currency)}).
+		Attrs(models.LedgerAccount{Name: code, Kind: kind, Currency: currency}).
+		FirstOrCreate(&account).Error
+	if err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// CustomerAccount returns the ledger account backing a customer account,
+// creating it on first use. Accounts that predate the ledger get an opening
+// balance entry against suspense so their derived balance matches.
+func CustomerAccount(tx *gorm.DB, account *models.Account) (*models.LedgerAccount, error) {
+	var ledgerAccount models.LedgerAccount
+	err := tx.Where("account_id = ?", account.ID).First(&ledgerAccount).Error
+	if err == nil {
+		return &ledgerAccount, nil
+	}
+	if !gorm.IsRecordNotFoundError(err) {
+		return nil, err
+	}
+
+	ledgerAccount = models.LedgerAccount{
+		Code:      "customer:" + account.AccountNo,
+		Name:      account.AccountType,
+		Kind:      KindLiability,
+		Currency:  account.Currency,
+		AccountID: &account.ID,
+	}
+	if err := tx.Create(&ledgerAccount).Error; err != nil {
+		return nil, err
+	}
+
+	if account.Balance.IsPositive() {
+		suspense, err := SystemAccount(tx, Suspense, account.Currency)
+		if err != nil {
+			return nil, err
+		}
+		// The cached balance already includes this amount, so only the
+		// postings are written
+		_, err = post(tx, Entry{
+			Kind:        "opening_balance",
+			Description: "Opening balance carried over for " + account.AccountNo,
+			Lines: []Line{
+				DebitLine(suspense, account.Balance),
+				CreditLine(&ledgerAccount, account.Balance),
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &ledgerAccount, nil
+}
+
+// Post validates and writes a journal entry, then applies it to the cached
+// balance of every customer account it touched. It must be called inside a
+// database transaction.
+func Post(tx *gorm.DB, entry Entry) (*models.JournalEntry, error) {
+	journal, err := post(tx, entry)
+	if err != nil {
+		return nil, err
+	}
+
+	// Net movement per customer account, signed like its balance
+	deltas := map[uint]money.Amount{}
+	var accountIDs []uint
+	for _, line := range entry.Lines {
+		if line.Account.AccountID == nil {
+			continue
+		}
+		id := *line.Account.AccountID
+		if _, ok := deltas[id]; !ok {
+			accountIDs = append(accountIDs, id)
+		}
+		var err error
+		if line.Direction == normalSide(line.Account) {
+			deltas[id], err = deltas[id].Add(line.Amount)
+		} else {
+			deltas[id], err = deltas[id].Sub(line.Amount)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, id := range accountIDs {
+		if deltas[id].IsZero() {
+			continue
+		}
+		err := tx.Model(&models.Account{}).
+			Where("id = ?", id).
+			Update("balance_minor", gorm.Expr("balance_minor + ?", deltas[id])).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+	return journal, nil
+}
+
+// post validates and writes a journal entry without touching cached
+// balances.
+func post(tx *gorm.DB, entry Entry) (*models.JournalEntry, error) {
+	if err := validate(entry); err != nil {
+		return nil, err
+	}
+
+	journal := models.JournalEntry{
+		Kind:        entry.Kind,
+		Description: entry.Description,
+		PostedAt:    time.Now(),
+	}
+	for _, line := range entry.Lines {
+		journal.Postings = append(journal.Postings, models.Posting{
+			LedgerAccountID: line.Account.ID,
+			Direction:       line.Direction,
+			Amount:          line.Amount,
+			Currency:        line.Account.Currency,
+		})
+	}
+	if err := tx.Create(&journal).Error; err != nil {
+		return nil, err
+	}
+	return &journal, nil
+}
+
+// normalSide is the direction that increases an account's balance.
+func normalSide(account *models.LedgerAccount) string {
+	if account.Kind == KindAsset || account.Kind == KindExpense {
+		return Debit
+	}
+	return Credit
+}
+
+func validate(entry Entry) error {
+	if len(entry.Lines) < 2 {
+		return ErrTooFewLines
+	}
+
+	// Debits minus credits, per currency, must come to zero
+	net := map[money.Currency]money.Amount{}
+	for _, line := range entry.Lines {
+		if line.Account == nil || !line.Amount.IsPositive() {
+			return ErrInvalidLine
+		}
+		var err error
+		switch line.Direction {
+		case Debit:
+			net[line.Account.Currency], err = net[line.Account.Currency].Add(line.Amount)
+		case Credit:
+			net[line.Account.Currency], err = net[line.Account.Currency].Sub(line.Amount)
+		default:
+			return fmt.Errorf("ledger: invalid direction %q", line.Direction)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	for _, amount := range net {
+		if !amount.IsZero() {
+			return ErrUnbalanced
+		}
+	}
+	return nil
+}
+
+// Balance derives a ledger account's balance from its postings, signed so
+// that the account's normal side is positive.
+func Balance(db *gorm.DB, account *models.LedgerAccount) (money.Amount, error) {
+	return balance(db.Model(&models.Posting{}), account)
+}
+
+// BalanceAt is Balance counting only journal entries posted before t.
+func BalanceAt(db *gorm.DB, account *models.LedgerAccount, t time.Time) (money.Amount, error) {
+	return balance(db.Model(&models.Posting{}).
+		Joins("JOIN journal_entries ON journal_entries.id = postings.journal_entry_id").
+		Where("journal_entries.posted_at < ?", t), account)
+}
+
+func balance(postings *gorm.DB, account *models.LedgerAccount) (money.Amount, error) {
+	var totals struct {
+		Debits  money.Amount
+		Credits money.Amount
+	}
+	err := postings.
+		Select("COALESCE(SUM(CASE WHEN direction = ? THEN amount_minor ELSE 0 END), 0) AS debits, "+
+			"COALESCE(SUM(CASE WHEN direction = ? THEN amount_minor ELSE 0 END), 0) AS credits", Debit, Credit).
+		Where("ledger_account_id = ?", account.ID).
+		Scan(&totals).Error
+	if err != nil {
+		return 0, err
+	}
+
+	if normalSide(account) == Debit {
+		return totals.Debits.Sub(totals.Credits)
+	}
+	return totals.Credits.Sub(totals.Debits)
+}
+
+// Reconcile recomputes a customer account's cached balance from every
+// posting against its ledger account, repairing any drift between the two.
+// It returns the derived balance.
+func Reconcile(tx *gorm.DB, ledgerAccount *models.LedgerAccount) (money.Amount, error) {
+	if ledgerAccount.AccountID == nil {
+		return 0, errors.New("ledger: not a customer account")
+	}
+	balance, err := Balance(tx, ledgerAccount)
+	if err != nil {
+		return 0, err
+	}
+	err = tx.Model(&models.Account{}).
+		Where("id = ?", *ledgerAccount.AccountID).
+		Update("balance_minor", balance).Error
+	return balance, err
+}
+
+// TrialBalanceLine totals every posting in one currency. The books balance
+// when Debits equals Credits.
+type TrialBalanceLine struct {
+	Currency money.Currency `json:"currency"`
+	Debits   money.Amount   `json:"debits"`
+	Credits  money.Amount   `json:"credits"`
+	Balanced bool           `json:"balanced"`
+}
+
+// TrialBalance totals all postings per currency.
+func TrialBalance(db *gorm.DB) ([]TrialBalanceLine, error) {
+	var lines []TrialBalanceLine
+	err := db.Model(&models.Posting{}).
+		Select("currency, "+
+			"COALESCE(SUM(CASE WHEN direction = ? THEN amount_minor ELSE 0 END), 0) AS debits, "+
+			"COALESCE(SUM(CASE WHEN direction = ? THEN amount_minor ELSE 0 END), 0) AS credits", Debit, Credit).
+		Group("currency").
+		Order("currency").
+		Scan(&lines).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range lines {
+		lines[i].Balanced = lines[i].Debits == lines[i].Credits
+	}
+	return lines, nil
+}
@@ -0,0 +1,105 @@
+package ledger
+
+import (
+	"bank-app/models"
+	"bank-app/money"
+	"errors"
+	"math"
+	"testing"
+)
+
+func account(id uint, kind string, currency money.Currency) *models.LedgerAccount {
+	a := &models.LedgerAccount{Kind: kind, Currency: currency}
+	a.ID = id
+	return a
+}
+
+func TestValidate(t *testing.T) {
+	cashUSD := account(1, KindAsset, money.USD)
+	customerUSD := account(2, KindLiability, money.USD)
+	feesUSD := account(3, KindIncome, money.USD)
+	customerEUR := account(4, KindLiability, money.EUR)
+	fxUSD := account(5, KindLiability, money.USD)
+	fxEUR := account(6, KindLiability, money.EUR)
+
+	cases := []struct {
+		name  string
+		lines []Line
+		err   error
+	}{
+		{"balanced", []Line{DebitLine(cashUSD, 100), CreditLine(customerUSD, 100)}, nil},
+		{"split credit", []Line{
+			DebitLine(customerUSD, 105),
+			CreditLine(cashUSD, 100),
+			CreditLine(feesUSD, 5),
+		}, nil},
+		{"each currency balances", []Line{
+			DebitLine(customerUSD, 100),
+			CreditLine(fxUSD, 100),
+			DebitLine(fxEUR, 92),
+			CreditLine(customerEUR, 92),
+		}, nil},
+
+		{"no lines", nil, ErrTooFewLines},
+		{"one line", []Line{DebitLine(cashUSD, 100)}, ErrTooFewLines},
+		{"unbalanced", []Line{DebitLine(cashUSD, 100), CreditLine(customerUSD, 99)}, ErrUnbalanced},
+		{"both debits", []Line{DebitLine(cashUSD, 100), DebitLine(customerUSD, 100)}, ErrUnbalanced},
+		// Equal totals across currencies still leave each one unbalanced
+		{"mixed currencies", []Line{DebitLine(customerUSD, 100), CreditLine(customerEUR, 100)}, ErrUnbalanced},
+		{"mixed currencies, one balanced", []Line{
+			DebitLine(cashUSD, 100),
+			CreditLine(customerUSD, 100),
+			DebitLine(fxEUR, 50),
+			CreditLine(customerUSD, 50),
+		}, ErrUnbalanced},
+		{"zero amount", []Line{DebitLine(cashUSD, 0), CreditLine(customerUSD, 0)}, ErrInvalidLine},
+		{"negative amount", []Line{DebitLine(cashUSD, -100), CreditLine(customerUSD, -100)}, ErrInvalidLine},
+		{"empty line", []Line{DebitLine(cashUSD, 100), CreditLine(customerUSD, 100), {}}, ErrInvalidLine},
+		{"no account", []Line{DebitLine(nil, 100), CreditLine(customerUSD, 100)}, ErrInvalidLine},
+		{"overflow", []Line{
+			DebitLine(cashUSD, math.MaxInt64),
+			DebitLine(cashUSD, 1),
+			CreditLine(customerUSD, math.MaxInt64),
+			CreditLine(customerUSD, 1),
+		}, money.ErrOverflow},
+	}
+	for _, tc := range cases {
+		if err := validate(Entry{Kind: "test", Lines: tc.lines}); !errors.Is(err, tc.err) {
+			t.Errorf("%s: got %v, want %v", tc.name, err, tc.err)
+		}
+	}
+
+	bad := Entry{Lines: []Line{{Account: cashUSD, Direction: "sideways", Amount: 100}, CreditLine(customerUSD, 100)}}
+	if err := validate(bad); err == nil {
+		t.Error("unknown direction accepted")
+	}
+}
+
+// Post must reject an invalid entry before it writes anything.
+func TestPostRejectsInvalidEntries(t *testing.T) {
+	entries := []Entry{
+		{},
+		{Lines: []Line{DebitLine(account(1, KindAsset, money.USD), 100), CreditLine(account(2, KindLiability, money.USD), 50)}},
+		{Lines: []Line{DebitLine(account(1, KindAsset, money.USD), 100), CreditLine(account(2, KindLiability, money.EUR), 100)}},
+	}
+	for i, entry := range entries {
+		// A nil transaction panics if Post gets as far as using it
+		if _, err := Post(nil, entry); err == nil {
+			t.Errorf("entry %d: posted", i)
+		}
+	}
+}
+
+func TestNormalSide(t *testing.T) {
+	cases := map[string]string{
+		KindAsset:     Debit,
+		KindExpense:   Debit,
+		KindLiability: Credit,
+		KindIncome:    Credit,
+	}
+	for kind, want := range cases {
+		if got := normalSide(&models.LedgerAccount{Kind: kind}); got != want {
+			t.Errorf("normalSide(%s) = %s, want %s", kind, got, want)
+		}
+	}
+}
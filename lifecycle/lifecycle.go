@@ -0,0 +1,68 @@
+package lifecycle
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Account statuses.
+const (
+	// Opened but not yet in use; it can be funded but not drawn on
+	Pending = "pending"
+	Active  = "active"
+	// Blocked by the bank, e.g. during a fraud investigation
+	Frozen = "frozen"
+	// Unused for a long time; money can come in but not go out until the
+	// account is reactivated
+	Dormant = "dormant"
+	// Permanently closed
+	Closed = "closed"
+)
+
+// Operations an account status may permit.
+const (
+	Deposit     = "deposit"
+	Withdraw    = "withdraw"
+	TransferOut = "transfer_out"
+	TransferIn  = "transfer_in"
+)
+
+var ErrInvalidTransition = errors.New("lifecycle: status change not allowed")
+
+// A frozen account can be closed, but nothing can be paid out of it, so only
+// once its balance is zero.
+var transitions = map[string]map[string]bool{
+	Pending: {Active: true, Frozen: true, Closed: true},
+	Active:  {Frozen: true, Dormant: true, Closed: true},
+	Dormant: {Active: true, Frozen: true, Closed: true},
+	Frozen:  {Active: true, Closed: true},
+	Closed:  {},
+}
+
+var permitted = map[string]map[string]bool{
+	Pending: {Deposit: true, TransferIn: true},
+	Active:  {Deposit: true, Withdraw: true, TransferOut: true, TransferIn: true},
+	Dormant: {Deposit: true, TransferIn: true},
+	Frozen:  {},
+	Closed:  {},
+}
+
+// Valid reports whether status is a known account status.
+func Valid(status string) bool {
+	_, ok := transitions[status]
+	return ok
+}
+
+// CanTransition reports whether an account may move from one status to
+// another.
+func CanTransition(from, to string) error {
+	if !transitions[from][to] {
+		return fmt.Errorf("%w: %s to %s", ErrInvalidTransition, from, to)
+	}
+	return nil
+}
+
+// Permits reports whether an account in status allows operation.
+func Permits(status, operation string) bool {
+	return permitted[status][operation]
+}
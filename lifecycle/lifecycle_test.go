@@ -0,0 +1,53 @@
+package lifecycle
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCanTransition(t *testing.T) {
+	allowed := map[string][]string{
+		Pending: {Active, Frozen, Closed},
+		Active:  {Frozen, Dormant, Closed},
+		Dormant: {Active, Frozen, Closed},
+		Frozen:  {Active, Closed},
+		Closed:  nil,
+	}
+	statuses := []string{Pending, Active, Frozen, Dormant, Closed}
+
+	for _, from := range statuses {
+		want := map[string]bool{}
+		for _, to := range allowed[from] {
+			want[to] = true
+		}
+		for _, to := range statuses {
+			err := CanTransition(from, to)
+			if want[to] && err != nil {
+				t.Errorf("%s to %s: %v", from, to, err)
+			}
+			if !want[to] && !errors.Is(err, ErrInvalidTransition) {
+				t.Errorf("%s to %s: got %v, want ErrInvalidTransition", from, to, err)
+			}
+		}
+	}
+
+	if err := CanTransition("unknown", Active); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("from an unknown status: got %v", err)
+	}
+}
+
+func TestPermits(t *testing.T) {
+	for _, operation := range []string{Deposit, Withdraw, TransferOut, TransferIn} {
+		if !Permits(Active, operation) {
+			t.Errorf("active account refuses %s", operation)
+		}
+		if Permits(Frozen, operation) || Permits(Closed, operation) {
+			t.Errorf("frozen or closed account allows %s", operation)
+		}
+	}
+	for _, status := range []string{Pending, Dormant} {
+		if !Permits(status, Deposit) || !Permits(status, TransferIn) || Permits(status, Withdraw) || Permits(status, TransferOut) {
+			t.Errorf("%s account should take money in but not pay it out", status)
+		}
+	}
+}
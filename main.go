@@ -1,12 +1,17 @@
 package main
 
 import (
+	"bank-app/authz"
 	"bank-app/config"
+	"bank-app/fees"
 	"bank-app/handlers"
+	"bank-app/interest"
 	"bank-app/middleware"
-	"bank-app/rabbitmq"
+	"bank-app/outbox"
+	"context"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -26,13 +31,45 @@ func main() {
 	config.ConnectDB()
 	defer config.CloseDB()
 
-	if err := rabbitmq.Init(); err != nil {
-		log.Fatalf("Failed to initialize RabbitMQ: %v", err)
+	if err := config.LoadJWTKeys(); err != nil {
+		log.Fatalf("Failed to load JWT keys: %v", err)
 	}
-	defer rabbitmq.Close()
+
+	if err := config.LoadFXRates(); err != nil {
+		log.Fatalf("Failed to load FX rates: %v", err)
+	}
+
+	publisher, err := config.NewEventPublisher()
+	if err != nil {
+		log.Fatalf("Failed to initialize event publisher: %v", err)
+	}
+	defer publisher.Close()
+
+	// Relay events written to the outbox by the handlers to the publisher
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go outbox.NewRelay(config.DB, publisher).Run(ctx)
+	go outbox.Sweep(ctx, config.DB, config.OutboxRetention(), time.Hour)
+	go middleware.SweepIdempotencyKeys(ctx, time.Hour)
+
+	// Accrue interest daily and pay it in monthly
+	interestEngine := interest.NewEngine(config.DB)
+	interestEngine.Interval = config.InterestRunInterval()
+	go interestEngine.Run(ctx)
+
+	// Charge monthly maintenance and minimum balance fees
+	feeEngine := fees.NewEngine(config.DB)
+	feeEngine.Interval = config.FeeRunInterval()
+	go feeEngine.Run(ctx)
 
 	// Set up the Gin router
 	r := gin.Default()
+	// Client IPs feed login throttling, so forwarding headers are only
+	// believed from configured proxies
+	if err := r.SetTrustedProxies(config.TrustedProxies()); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+	r.Use(middleware.CorrelationIDMiddleware())
 
 	// Add this before defining routes in `main.go`
 	// r.Use(cors.New(cors.Config{
@@ -49,35 +86,89 @@ func main() {
 		})
 	})
 
+	r.GET("/healthz", handlers.HealthCheck(publisher))
+	r.GET("/.well-known/jwks.json", handlers.JWKS)
+
 	r.POST("/signup", handlers.SignUp)
 	r.POST("/login", handlers.Login)
-	r.GET("/transactions/summary", handlers.GetAllTransactionsSummary)
+	r.POST("/login/totp", handlers.LoginTOTP)
+	r.POST("/token/refresh", handlers.RefreshToken)
+	r.POST("/email/verify", handlers.VerifyEmail)
+	r.POST("/password/forgot", handlers.ForgotPassword)
+	r.POST("/password/reset", handlers.ResetPassword)
 
+	// Everything below accepts a bearer token or an API key. Routes that
+	// manage the caller's own credentials need a real login session
 	auth := r.Group("/")
-	auth.Use(middleware.JWTAuthMiddleware())
+	auth.Use(middleware.AuthMiddleware())
+	session := middleware.RequireSession()
+
+	auth.GET("/transactions/summary", middleware.RequirePermission(authz.PermViewReports), handlers.GetAllTransactionsSummary)
+
+	auth.POST("/logout", session, handlers.Logout)
+	auth.POST("/reauth", session, handlers.Reauthenticate)
+	auth.POST("/email/verification", session, handlers.ResendEmailVerification)
 
 	// Routes for users
 	// r.POST("/users", handlers.CreateUser)
 	auth.GET("/users/:id", handlers.GetUserByID)
+	auth.PUT("/users/me/password", session, handlers.ChangePassword)
+	auth.POST("/users/me/totp/enroll", session, handlers.EnrollTOTP)
+	auth.POST("/users/me/totp/confirm", session, handlers.ConfirmTOTP)
+	auth.POST("/users/me/api-keys", session, handlers.CreateAPIKey)
+	auth.GET("/users/me/api-keys", session, handlers.GetAPIKeys)
+	auth.DELETE("/users/me/api-keys/:id", session, handlers.RevokeAPIKey)
 	auth.GET("/accounts", handlers.GetAllAccounts)
+	auth.GET("/products", handlers.GetAccountProducts)
+	auth.GET("/products/:code", handlers.GetAccountProduct)
 
 	// Routes for accounts
-	auth.POST("/accounts", handlers.CreateAccount)
-	auth.POST("/accounts/:account_no/deposit", handlers.Deposit)
-	auth.POST("/accounts/:account_no/withdraw", handlers.Withdraw)
+	transact := middleware.RequirePermission(authz.PermTransact)
+	verified := middleware.RequireVerifiedEmail()
+	auth.POST("/accounts", transact, verified, handlers.CreateAccount)
+	auth.POST("/accounts/:account_no/deposit", transact, verified, middleware.IdempotencyMiddleware(), handlers.Deposit)
+	auth.POST("/accounts/:account_no/withdraw", transact, verified, middleware.IdempotencyMiddleware(), handlers.Withdraw)
+	auth.POST("/accounts/:account_no/close", transact, verified, middleware.IdempotencyMiddleware(), handlers.CloseAccount)
+	auth.GET("/accounts/:account_no/status-history", handlers.GetAccountStatusHistory)
+	auth.GET("/accounts/:account_no/interest", handlers.GetInterestHistory)
 
 	// Update the transfer route to avoid conflict
-	auth.POST("/accounts/transfer/:from_account/:to_account", handlers.Transfer)
+	auth.POST("/accounts/transfer/:from_account/:to_account", transact, verified, middleware.IdempotencyMiddleware(), handlers.Transfer)
+	auth.POST("/accounts/transfer/:from_account/:to_account/quote", transact, handlers.QuoteTransfer)
 
 	auth.GET("/transactions/:id", handlers.GetTransactionByID)
 	auth.GET("/users/:id/transactions", handlers.GetTransactionsByUserID)
 	auth.GET("/accounts/:account_no/transactions", handlers.GetTransactionsByAccountNo)
 
+	// Read-only access to the general ledger
+	ledgerRead := middleware.RequirePermission(authz.PermReadLedger)
+	auth.GET("/ledger/accounts", ledgerRead, handlers.GetLedgerAccounts)
+	auth.GET("/ledger/entries/:id", ledgerRead, handlers.GetJournalEntry)
+	auth.GET("/ledger/trial-balance", ledgerRead, handlers.GetTrialBalance)
+
+	// Administration
+	manageUsers := middleware.RequirePermission(authz.PermManageUsers)
+	auth.PUT("/admin/users/:id/role", manageUsers, handlers.UpdateUserRole)
+	auth.POST("/admin/users/:id/unlock", manageUsers, handlers.UnlockUser)
+
+	manageAccounts := middleware.RequirePermission(authz.PermManageAccounts)
+	auth.POST("/admin/accounts/:account_no/freeze", manageAccounts, handlers.FreezeAccount)
+	auth.POST("/admin/accounts/:account_no/unfreeze", manageAccounts, handlers.UnfreezeAccount)
+	auth.PUT("/admin/accounts/:account_no/status", manageAccounts, handlers.UpdateAccountStatus)
+	auth.POST("/admin/fee-waivers", manageAccounts, handlers.CreateFeeWaiver)
+	auth.GET("/admin/fee-waivers", manageAccounts, handlers.GetFeeWaivers)
+	auth.DELETE("/admin/fee-waivers/:id", manageAccounts, handlers.DeleteFeeWaiver)
+
+	manageProducts := middleware.RequirePermission(authz.PermManageProducts)
+	auth.POST("/admin/products", manageProducts, handlers.CreateAccountProduct)
+	auth.PUT("/admin/products/:code", manageProducts, handlers.UpdateAccountProduct)
+	auth.DELETE("/admin/products/:code", manageProducts, handlers.DeleteAccountProduct)
+
 	// Start the server on the port from the environment or default to 7070
 	port := "8080"
 
 	// Start the Gin server
-	err := r.Run(fmt.Sprintf(":%s", port))
+	err = r.Run(fmt.Sprintf(":%s", port))
 	if err != nil {
 		log.Fatal("Failed to start server: ", err)
 	}
@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"bank-app/authz"
+	"bank-app/config"
+	"bank-app/models"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// apiKeyTouchInterval limits how often last_used_at is written for a busy key.
+const apiKeyTouchInterval = time.Minute
+
+// APIKeyAuthMiddleware authenticates "Authorization: ApiKey <key>" requests
+// as the key's owner. Read requests need the read scope; other requests are
+// limited by RequirePermission to what the key's scopes allow.
+func APIKeyAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authHeader := c.GetHeader("Authorization")
+		if !strings.HasPrefix(authHeader, "ApiKey ") {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authorization header missing or malformed"})
+			return
+		}
+
+		key := strings.TrimPrefix(authHeader, "ApiKey ")
+		prefix, ok := config.APIKeyPrefix(key)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key"})
+			return
+		}
+
+		var apiKey models.APIKey
+		if err := config.DB.Where("prefix = ?", prefix).First(&apiKey).Error; err != nil ||
+			subtle.ConstantTimeCompare([]byte(apiKey.KeyHash), []byte(config.HashToken(key))) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key"})
+			return
+		}
+		now := time.Now()
+		if apiKey.RevokedAt != nil || (apiKey.ExpiresAt != nil && apiKey.ExpiresAt.Before(now)) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "API key has expired or been revoked"})
+			return
+		}
+
+		// The key acts with its owner's current role
+		var user models.User
+		if err := config.DB.First(&user, apiKey.UserID).Error; err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key"})
+			return
+		}
+
+		scopes := apiKey.ScopeList()
+		if (c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead) && !authz.HasScope(scopes, authz.ScopeRead) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "API key lacks the read scope"})
+			return
+		}
+
+		if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) > apiKeyTouchInterval {
+			config.DB.Model(&apiKey).UpdateColumn("last_used_at", &now)
+		}
+
+		c.Set("userID", user.ID)
+		c.Set("role", user.Role)
+		c.Set("apiKeyID", apiKey.ID)
+		c.Set("scopes", scopes)
+		c.Next()
+	}
+}
+
+// AuthMiddleware accepts either a bearer access token or an API key.
+func AuthMiddleware() gin.HandlerFunc {
+	jwtAuth := JWTAuthMiddleware()
+	apiKeyAuth := APIKeyAuthMiddleware()
+	return func(c *gin.Context) {
+		if strings.HasPrefix(c.GetHeader("Authorization"), "ApiKey ") {
+			apiKeyAuth(c)
+			return
+		}
+		jwtAuth(c)
+	}
+}
+
+// RequireSession rejects API key requests, for routes that act on the
+// caller's login session or credentials. It must run after AuthMiddleware.
+func RequireSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := c.Get("sessionID"); !ok {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "This endpoint requires a user session"})
+			return
+		}
+		c.Next()
+	}
+}
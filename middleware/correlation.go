@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"bank-app/events"
+
+	"github.com/gin-gonic/gin"
+)
+
+const CorrelationIDHeader = "X-Correlation-ID"
+
+// CorrelationIDMiddleware tags each request with a correlation ID, taken from
+// the X-Correlation-ID header when the caller sends one, so that events
+// published while handling it can be traced back to the request.
+func CorrelationIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		correlationID := c.GetHeader(CorrelationIDHeader)
+		if correlationID == "" || len(correlationID) > 128 {
+			correlationID = events.NewID()
+		}
+
+		c.Set("correlationID", correlationID)
+		c.Header(CorrelationIDHeader, correlationID)
+		c.Next()
+	}
+}
@@ -0,0 +1,172 @@
+package middleware
+
+import (
+	"bank-app/config"
+	"bank-app/models"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// responseRecorder keeps a copy of everything written to the client so the
+// response can be stored against the idempotency key.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// IdempotencyMiddleware makes a handler safe to retry. The first request
+// carrying an Idempotency-Key header runs normally and its response is
+// stored; repeats of the same request under that key replay the stored
+// response, and a different request under the same key is rejected.
+// Requests without the header are passed through unchanged. It must run
+// after JWTAuthMiddleware.
+func IdempotencyMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > 255 {
+			c.AbortWithStatusJSON(http.StatusBadRequest, models.ErrorResponse{Message: "Idempotency-Key is too long"})
+			return
+		}
+
+		userID := c.MustGet("userID").(uint)
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, models.ErrorResponse{Message: "Failed to read request body"})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		fingerprint := requestFingerprint(c.Request.Method, c.Request.URL.Path, body)
+
+		var record models.IdempotencyKey
+		err = config.DB.Where("user_id = ? AND idempotency_key = ?", userID, key).First(&record).Error
+		if err == nil && (record.ExpiresAt.Before(time.Now()) || abandoned(record)) {
+			// Expired keys are forgotten and the request runs afresh, as are
+			// keys whose request never finished, e.g. because the server
+			// crashed; its database transaction was rolled back
+			config.DB.Unscoped().Delete(&record)
+			err = config.DB.Where("user_id = ? AND idempotency_key = ?", userID, key).First(&record).Error
+		}
+
+		if err == nil {
+			replayOrReject(c, record, fingerprint)
+			return
+		}
+
+		record = models.IdempotencyKey{
+			UserID:      userID,
+			Key:         key,
+			Fingerprint: fingerprint,
+			ExpiresAt:   time.Now().Add(config.IdempotencyKeyTTL()),
+		}
+		if err := config.DB.Create(&record).Error; err != nil {
+			// A concurrent request claimed the key first
+			var existing models.IdempotencyKey
+			if config.DB.Where("user_id = ? AND idempotency_key = ?", userID, key).First(&existing).Error == nil {
+				replayOrReject(c, existing, fingerprint)
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to record idempotency key"})
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		// Server errors are not remembered so that the client can retry them,
+		// nor are 401s, which the client retries after re-authenticating
+		if recorder.Status() >= http.StatusInternalServerError || recorder.Status() == http.StatusUnauthorized {
+			config.DB.Unscoped().Delete(&record)
+			return
+		}
+
+		if err := config.DB.Model(&record).Updates(map[string]interface{}{
+			"completed":     true,
+			"status_code":   recorder.Status(),
+			"response_body": recorder.body.String(),
+		}).Error; err != nil {
+			// Without a stored response the key would answer 409 until it
+			// expires, so give it up and let the client retry
+			log.Printf("Failed to store response for idempotency key %d: %v\n", record.ID, err)
+			config.DB.Unscoped().Delete(&record)
+		}
+	}
+}
+
+// abandoned reports whether a key has been in progress for longer than any
+// request takes.
+func abandoned(record models.IdempotencyKey) bool {
+	return !record.Completed && record.CreatedAt.Before(time.Now().Add(-config.IdempotencyInFlightTimeout()))
+}
+
+// SweepIdempotencyKeys deletes expired idempotency keys every interval until
+// ctx is cancelled, so the table does not grow without bound.
+func SweepIdempotencyKeys(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		result := config.DB.Unscoped().Where("expires_at < ?", time.Now()).Delete(&models.IdempotencyKey{})
+		if result.Error != nil {
+			log.Printf("Idempotency key sweep failed: %v\n", result.Error)
+		} else if result.RowsAffected > 0 {
+			log.Printf("Deleted %d expired idempotency keys\n", result.RowsAffected)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func replayOrReject(c *gin.Context, record models.IdempotencyKey, fingerprint string) {
+	if record.Fingerprint != fingerprint {
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, models.ErrorResponse{Message: "Idempotency-Key was already used with a different request"})
+		return
+	}
+	if !record.Completed {
+		c.AbortWithStatusJSON(http.StatusConflict, models.ErrorResponse{Message: "A request with this Idempotency-Key is still in progress"})
+		return
+	}
+
+	c.Header("Idempotent-Replayed", "true")
+	c.Data(record.StatusCode, "application/json; charset=utf-8", []byte(record.ResponseBody))
+	c.Abort()
+}
+
+func requestFingerprint(method, path string, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(method))
+	hash.Write([]byte{0})
+	hash.Write([]byte(path))
+	hash.Write([]byte{0})
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
@@ -2,8 +2,10 @@ package middleware
 
 import (
 	"bank-app/config"
+	"bank-app/models"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
@@ -18,9 +20,7 @@ func JWTAuthMiddleware() gin.HandlerFunc {
 		}
 
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			return config.JWTSecret, nil
-		})
+		token, err := config.JWTKeys.Parse(tokenString)
 
 		if err != nil || !token.Valid {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
@@ -33,9 +33,39 @@ func JWTAuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		// Attach userID to the context
-		userID := uint(claims["user_id"].(float64))
+		userIDClaim, ok := claims["user_id"].(float64)
+		sessionIDClaim, hasSession := claims["sid"].(float64)
+		role, hasRole := claims["role"].(string)
+		if !ok || !hasSession || !hasRole {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid claims"})
+			return
+		}
+
+		// Reject tokens whose session has been logged out or revoked
+		var session models.Session
+		if err := config.DB.First(&session, uint(sessionIDClaim)).Error; err != nil || session.RevokedAt != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Session has been revoked"})
+			return
+		}
+
+		// Attach userID, session and role to the context
+		userID := uint(userIDClaim)
+		if session.UserID != userID {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid claims"})
+			return
+		}
 		c.Set("userID", userID)
+		c.Set("sessionID", session.ID)
+		c.Set("role", role)
+
+		// When and how the user last authenticated, for step-up checks.
+		// Tokens without auth_time never count as recent
+		if authTime, ok := claims["auth_time"].(float64); ok {
+			c.Set("authTime", time.Unix(int64(authTime), 0))
+		}
+		if acr, ok := claims["acr"].(string); ok {
+			c.Set("acr", acr)
+		}
 		c.Next()
 	}
 }
@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"bank-app/authz"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequirePermission rejects requests whose role does not grant permission,
+// or that use an API key whose scopes do not cover it. It must run after
+// JWTAuthMiddleware or AuthMiddleware.
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !authz.HasPermission(c.GetString("role"), permission) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+			return
+		}
+		if scopes, ok := c.Get("scopes"); ok && !authz.ScopesAllow(scopes.([]string), permission) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+			return
+		}
+		c.Next()
+	}
+}
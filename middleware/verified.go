@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"bank-app/config"
+	"bank-app/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireVerifiedEmail rejects requests from users who have not verified
+// their email address yet. It must run after JWTAuthMiddleware.
+func RequireVerifiedEmail() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var user models.User
+		if err := config.DB.Select("id, email_verified_at").First(&user, c.MustGet("userID").(uint)).Error; err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+			return
+		}
+		if user.EmailVerifiedAt == nil {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Verify your email address before moving money"})
+			return
+		}
+		c.Next()
+	}
+}
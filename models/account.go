@@ -1,11 +1,34 @@
 package models
 
-import "github.com/jinzhu/gorm"
+import (
+	"bank-app/money"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
 
 type Account struct {
-	gorm.Model  `swaggerignore:"true"`
-	UserID      uint    `json:"user_id"`
-	AccountNo   string  `json:"account_no" gorm:"unique;not null"`
-	Balance     float64 `json:"balance"`
-	AccountType string  `json:"account_type"`
+	gorm.Model      `swaggerignore:"true"`
+	UserID          uint           `json:"user_id"`
+	AccountNo       string         `json:"account_no" gorm:"unique;not null"`
+	Balance         money.Amount   `json:"balance" gorm:"column:balance_minor;type:bigint;not null;default:0"`
+	Currency        money.Currency `json:"currency" gorm:"type:char(3);not null;default:'USD'"`
+	AccountType     string         `json:"account_type"`
+	Status          string         `json:"status" gorm:"size:16;not null;default:'active'"`
+	StatusReason    string         `json:"status_reason,omitempty"`
+	StatusChangedAt *time.Time     `json:"status_changed_at,omitempty"`
+	// Last day interest has been worked out for, whether or not any was due
+	InterestAccruedThrough *time.Time `json:"-" gorm:"type:date"`
+	// Last day of the last month monthly fees were assessed for
+	FeesChargedThrough *time.Time `json:"-" gorm:"type:date"`
+}
+
+// AccountStatusChange is the audit trail of an account's status.
+type AccountStatusChange struct {
+	gorm.Model `swaggerignore:"true"`
+	AccountID  uint   `json:"account_id" gorm:"index;not null"`
+	FromStatus string `json:"from_status" gorm:"size:16;not null"`
+	ToStatus   string `json:"to_status" gorm:"size:16;not null"`
+	Reason     string `json:"reason"`
+	ChangedBy  uint   `json:"changed_by"`
 }
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"bank-app/money"
+
+	"github.com/jinzhu/gorm"
+)
+
+// AccountProduct is an account type customers can open. Accounts refer to
+// their product by Code through AccountType.
+type AccountProduct struct {
+	gorm.Model `swaggerignore:"true"`
+	Code       string `json:"code" gorm:"size:32;unique_index;not null"`
+	Name       string `json:"name" gorm:"not null"`
+	// Empty lets the customer pick any supported currency, and is only
+	// allowed for products without money amounts
+	Currency          money.Currency `json:"currency,omitempty" gorm:"type:char(3)"`
+	MinOpeningBalance money.Amount   `json:"min_opening_balance" gorm:"column:min_opening_balance_minor;type:bigint;not null;default:0"`
+	// Open accounts a user may hold at once; zero means no limit
+	MaxAccountsPerUser int            `json:"max_accounts_per_user" gorm:"not null;default:0"`
+	InterestDayCount   string         `json:"interest_day_count" gorm:"size:8"`
+	InterestTiers      []InterestTier `json:"interest_tiers" gorm:"foreignkey:ProductID"`
+	FeeRules           []FeeRule      `json:"fee_rules" gorm:"foreignkey:ProductID"`
+}
+
+// InterestTier pays RateBps a year on balances of at least MinBalance.
+type InterestTier struct {
+	gorm.Model `swaggerignore:"true"`
+	ProductID  uint         `json:"-" gorm:"index;not null"`
+	MinBalance money.Amount `json:"min_balance" gorm:"column:min_balance_minor;type:bigint;not null;default:0"`
+	RateBps    int          `json:"rate_bps" gorm:"not null"`
+}
+
+// FeeRule charges Amount for one kind of fee. MinimumBalance applies to the
+// minimum balance fee and FreeCount to excess withdrawals.
+type FeeRule struct {
+	gorm.Model     `swaggerignore:"true"`
+	ProductID      uint         `json:"-" gorm:"index;not null"`
+	Kind           string       `json:"kind" gorm:"size:32;not null"`
+	Amount         money.Amount `json:"amount" gorm:"column:amount_minor;type:bigint;not null"`
+	MinimumBalance money.Amount `json:"minimum_balance" gorm:"column:minimum_balance_minor;type:bigint;not null;default:0"`
+	FreeCount      int          `json:"free_count" gorm:"not null;default:0"`
+}
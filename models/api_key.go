@@ -0,0 +1,31 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// APIKey lets a server-to-server client act as the user who owns it. The
+// full key is shown once at creation; only its prefix, which identifies it,
+// and a SHA-256 hash of the whole key are stored.
+type APIKey struct {
+	gorm.Model
+	UserID     uint   `gorm:"index;not null"`
+	Name       string `gorm:"size:100;not null"`
+	Prefix     string `gorm:"size:16;unique_index;not null"`
+	KeyHash    string `gorm:"size:64;not null"`
+	Scopes     string `gorm:"size:255;not null"`
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+}
+
+// ScopeList splits the stored comma-separated scopes.
+func (k APIKey) ScopeList() []string {
+	if k.Scopes == "" {
+		return nil
+	}
+	return strings.Split(k.Scopes, ",")
+}
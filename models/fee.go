@@ -0,0 +1,30 @@
+package models
+
+import (
+	"bank-app/money"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// FeeWaiver exempts a user, or every account on a product, from one kind of
+// fee, or from all of them when Kind is empty, until ExpiresAt if set.
+type FeeWaiver struct {
+	gorm.Model  `swaggerignore:"true"`
+	UserID      *uint      `json:"user_id,omitempty" gorm:"index"`
+	ProductCode string     `json:"product_code,omitempty" gorm:"size:32;index"`
+	Kind        string     `json:"kind,omitempty" gorm:"size:32"`
+	Reason      string     `json:"reason" gorm:"not null"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	CreatedBy   uint       `json:"created_by"`
+}
+
+// FeeCharge is one fee assessed on an account. Waived charges are recorded
+// but nothing is taken.
+type FeeCharge struct {
+	Kind          string       `json:"kind"`
+	Amount        money.Amount `json:"amount" swaggertype:"string" example:"1.50"`
+	Waived        bool         `json:"waived"`
+	WaiverID      *uint        `json:"waiver_id,omitempty"`
+	TransactionID *uint        `json:"transaction_id,omitempty"`
+}
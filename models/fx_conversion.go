@@ -0,0 +1,25 @@
+package models
+
+import (
+	"bank-app/money"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// FXConversion records the terms of a cross-currency transfer. MidAmount is
+// FromAmount at the mid-market Rate; the receiver got ToAmount and the bank
+// kept SpreadAmount, both in ToCurrency.
+type FXConversion struct {
+	gorm.Model     `swaggerignore:"true"`
+	JournalEntryID uint           `json:"journal_entry_id" gorm:"index;not null"`
+	FromCurrency   money.Currency `json:"from_currency" gorm:"type:char(3);not null"`
+	ToCurrency     money.Currency `json:"to_currency" gorm:"type:char(3);not null"`
+	FromAmount     money.Amount   `json:"from_amount" gorm:"column:from_amount_minor;type:bigint;not null"`
+	MidAmount      money.Amount   `json:"mid_amount" gorm:"column:mid_amount_minor;type:bigint;not null"`
+	ToAmount       money.Amount   `json:"to_amount" gorm:"column:to_amount_minor;type:bigint;not null"`
+	SpreadAmount   money.Amount   `json:"spread_amount" gorm:"column:spread_amount_minor;type:bigint;not null"`
+	Rate           string         `json:"rate" gorm:"size:32;not null"`
+	SpreadBps      int            `json:"spread_bps" gorm:"not null"`
+	RateAsOf       time.Time      `json:"rate_as_of"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// IdempotencyKey stores the outcome of a request made with an
+// Idempotency-Key header so that retries replay it instead of repeating it.
+type IdempotencyKey struct {
+	gorm.Model
+	UserID       uint      `gorm:"unique_index:idx_idempotency_user_key;not null"`
+	Key          string    `gorm:"column:idempotency_key;unique_index:idx_idempotency_user_key;size:255;not null"`
+	Fingerprint  string    `gorm:"size:64;not null"`
+	Completed    bool      `gorm:"not null;default:false"`
+	StatusCode   int       `gorm:"not null;default:0"`
+	ResponseBody string    `gorm:"type:text"`
+	ExpiresAt    time.Time `gorm:"index;not null"`
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"bank-app/money"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// InterestAccrual is one day's interest on an account's end-of-day balance:
+// Balance at RateBps a year, times the DayFraction of a year the day counts
+// for under DayCount.
+type InterestAccrual struct {
+	gorm.Model       `swaggerignore:"true"`
+	AccountID        uint         `json:"account_id" gorm:"unique_index:idx_interest_accrual_day;not null"`
+	AccrualDate      time.Time    `json:"accrual_date" gorm:"type:date;unique_index:idx_interest_accrual_day;not null"`
+	Balance          money.Amount `json:"balance" gorm:"column:balance_minor;type:bigint;not null"`
+	RateBps          int          `json:"rate_bps" gorm:"not null"`
+	DayCount         string       `json:"day_count" gorm:"size:8;not null"`
+	DayFraction      string       `json:"day_fraction" gorm:"size:16;not null"`
+	Accrued          money.Micros `json:"accrued" gorm:"column:accrued_micros;type:bigint;not null"`
+	CapitalizationID *uint        `json:"capitalization_id,omitempty" gorm:"index"`
+}
+
+// InterestCapitalization pays the interest accrued from PeriodStart to
+// PeriodEnd, inclusive, into the account. Amount is Accrued rounded to minor
+// units.
+type InterestCapitalization struct {
+	gorm.Model    `swaggerignore:"true"`
+	AccountID     uint         `json:"account_id" gorm:"index;not null"`
+	PeriodStart   time.Time    `json:"period_start" gorm:"type:date;not null"`
+	PeriodEnd     time.Time    `json:"period_end" gorm:"type:date;not null"`
+	Accrued       money.Micros `json:"accrued" gorm:"column:accrued_micros;type:bigint;not null"`
+	Amount        money.Amount `json:"amount" gorm:"column:amount_minor;type:bigint;not null"`
+	TransactionID *uint        `json:"transaction_id,omitempty"`
+}
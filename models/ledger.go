@@ -0,0 +1,38 @@
+package models
+
+import (
+	"bank-app/money"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// LedgerAccount is an account in the general ledger. Customer accounts have
+// AccountID set; system accounts (cash, clearing, fees, suspense) do not.
+type LedgerAccount struct {
+	gorm.Model `swaggerignore:"true"`
+	Code       string         `json:"code" gorm:"unique;not null"`
+	Name       string         `json:"name"`
+	Kind       string         `json:"kind"` // asset, liability, income, expense
+	Currency   money.Currency `json:"currency" gorm:"type:char(3);not null"`
+	AccountID  *uint          `json:"account_id,omitempty" gorm:"unique"`
+}
+
+// JournalEntry groups the postings of one business event. Its debits and
+// credits always balance per currency.
+type JournalEntry struct {
+	gorm.Model  `swaggerignore:"true"`
+	Kind        string    `json:"kind"` // deposit, withdrawal, transfer, fx_transfer, closure_payout, interest, fee, account_opening, opening_balance
+	Description string    `json:"description"`
+	PostedAt    time.Time `json:"posted_at"`
+	Postings    []Posting `json:"postings"`
+}
+
+type Posting struct {
+	gorm.Model      `swaggerignore:"true"`
+	JournalEntryID  uint           `json:"journal_entry_id" gorm:"index;not null"`
+	LedgerAccountID uint           `json:"ledger_account_id" gorm:"index;not null"`
+	Direction       string         `json:"direction"` // debit or credit
+	Amount          money.Amount   `json:"amount" gorm:"column:amount_minor;type:bigint;not null"`
+	Currency        money.Currency `json:"currency" gorm:"type:char(3);not null"`
+}
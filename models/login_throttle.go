@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// LoginThrottle counts recent failed logins for one subject: a lower-cased
+// email address (Scope "account") or a client IP address (Scope "ip").
+// Requests for verification and password reset emails are counted the same
+// way under Scopes "email" and "email_ip".
+type LoginThrottle struct {
+	gorm.Model
+	Scope         string `gorm:"size:16;not null;unique_index:idx_login_throttle_subject"`
+	Subject       string `gorm:"size:255;not null;unique_index:idx_login_throttle_subject"`
+	Failures      int    `gorm:"not null;default:0"`
+	LastFailureAt *time.Time
+	LockedUntil   *time.Time
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// OutboxEvent is an event waiting to be published to the message broker. It
+// is written in the same database transaction as the change it describes.
+type OutboxEvent struct {
+	gorm.Model
+	Payload       string    `gorm:"type:text;not null"`
+	Status        string    `gorm:"size:16;index;not null"` // pending, sent or dead
+	Attempts      int       `gorm:"not null;default:0"`
+	NextAttemptAt time.Time `gorm:"index;not null"`
+	LastError     string    `gorm:"type:text"`
+	SentAt        *time.Time
+}
@@ -0,0 +1,11 @@
+package models
+
+import "github.com/jinzhu/gorm"
+
+// PasswordHistory keeps the bcrypt hashes of a user's previous passwords so
+// they cannot be reused.
+type PasswordHistory struct {
+	gorm.Model
+	UserID       uint   `gorm:"index;not null"`
+	PasswordHash string `gorm:"not null"`
+}
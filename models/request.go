@@ -1,22 +1,70 @@
 package models
 
+import (
+	"bank-app/money"
+	"time"
+)
+
 type SignUpRequest struct {
 	FirstName string `json:"first_name" binding:"required"`
 	LastName  string `json:"last_name" binding:"required"`
 	Email     string `json:"email" binding:"required,email"`
-	Password  string `json:"password" binding:"required,min=8"`
+	Password  string `json:"password" binding:"required"`
 }
 
 type AccountRequest struct {
-	AccountType    string  `json:"account_type"`
-	InitialBalance float64 `json:"initial_balance"`
+	// Code of an account product, see GET /products
+	AccountType    string       `json:"account_type" example:"savings"`
+	InitialBalance money.Amount `json:"initial_balance" swaggertype:"string" example:"100.00"`
+	Currency       string       `json:"currency" example:"USD"`
+}
+
+// AccountProductRequest defines a product. On update the code in the path
+// is used and Code is ignored; tiers and fee rules replace the old ones.
+type AccountProductRequest struct {
+	Code               string                `json:"code" example:"savings"`
+	Name               string                `json:"name" example:"Savings"`
+	Currency           string                `json:"currency" example:"USD"`
+	MinOpeningBalance  money.Amount          `json:"min_opening_balance" swaggertype:"string" example:"0.00"`
+	MaxAccountsPerUser int                   `json:"max_accounts_per_user" example:"1"`
+	InterestDayCount   string                `json:"interest_day_count" example:"ACT/365"`
+	InterestTiers      []InterestTierRequest `json:"interest_tiers"`
+	FeeRules           []FeeRuleRequest      `json:"fee_rules"`
+}
+
+type InterestTierRequest struct {
+	MinBalance money.Amount `json:"min_balance" swaggertype:"string" example:"1000.00"`
+	RateBps    int          `json:"rate_bps" example:"150"`
+}
+
+type FeeRuleRequest struct {
+	Kind           string       `json:"kind" example:"monthly_maintenance"`
+	Amount         money.Amount `json:"amount" swaggertype:"string" example:"5.00"`
+	MinimumBalance money.Amount `json:"minimum_balance" swaggertype:"string" example:"0.00"`
+	FreeCount      int          `json:"free_count" example:"0"`
+}
+
+type AccountProductsResponse struct {
+	Products []AccountProduct `json:"products"`
 }
 
 type UserResponse struct {
-	ID        uint   `json:"id"`
-	FirstName string `json:"first_name"`
-	LastName  string `json:"last_name"`
-	Email     string `json:"email"`
+	ID            uint   `json:"id"`
+	FirstName     string `json:"first_name"`
+	LastName      string `json:"last_name"`
+	Email         string `json:"email"`
+	Role          string `json:"role"`
+	EmailVerified bool   `json:"email_verified"`
+}
+
+// UserDetailResponse is a user together with their accounts.
+type UserDetailResponse struct {
+	UserResponse
+	Accounts []Account `json:"accounts"`
+}
+
+type RoleRequest struct {
+	Role string `json:"role" binding:"required"`
 }
 
 type LoginRequest struct {
@@ -29,32 +77,207 @@ type ErrorResponse struct {
 }
 
 type AmountRequest struct {
-	Amount float64 `json:"amount"`
+	Amount money.Amount `json:"amount" swaggertype:"string" example:"25.00"`
 }
 
 type LoginResponse struct {
-	Message string       `json:"message"`
-	Token   string       `json:"token"`
-	User    UserResponse `json:"user"`
+	Message      string       `json:"message"`
+	Token        string       `json:"token"`
+	RefreshToken string       `json:"refresh_token"`
+	ExpiresIn    int          `json:"expires_in"`
+	User         UserResponse `json:"user"`
+}
+
+type MFAChallengeResponse struct {
+	Message        string `json:"message"`
+	MFARequired    bool   `json:"mfa_required"`
+	ChallengeToken string `json:"challenge_token"`
+	ExpiresIn      int    `json:"expires_in"`
+}
+
+type TOTPLoginRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code"`
+	RecoveryCode   string `json:"recovery_code"`
+}
+
+type TOTPEnrollResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+type TOTPCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type RecoveryCodesResponse struct {
+	Message       string   `json:"message"`
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type ReauthenticateRequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+type ReauthenticateResponse struct {
+	Message   string `json:"message"`
+	Token     string `json:"token"`
+	ExpiresIn int    `json:"expires_in"`
+}
+
+// StepUpChallengeResponse is returned with a 401 when an operation needs a
+// more recent authentication than the access token carries. The client
+// should call ReauthURL and retry with the new token.
+type StepUpChallengeResponse struct {
+	Error     string   `json:"error"`
+	Message   string   `json:"message"`
+	MaxAge    int      `json:"max_age"`
+	ACRValues []string `json:"acr_values"`
+	ReauthURL string   `json:"reauth_url"`
+}
+
+type TokenRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
+
+type CreateAPIKeyRequest struct {
+	Name          string   `json:"name" binding:"required,max=100"`
+	Scopes        []string `json:"scopes" binding:"required,min=1"`
+	ExpiresInDays int      `json:"expires_in_days" example:"90"`
+}
+
+type APIKeyResponse struct {
+	ID         uint       `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// CreatedAPIKeyResponse is the only response that includes the full key.
+type CreatedAPIKeyResponse struct {
+	APIKeyResponse
+	Key string `json:"key"`
+}
+
+type StatusReasonRequest struct {
+	Reason string `json:"reason" binding:"required,max=255"`
+}
+
+type AccountStatusRequest struct {
+	Status string `json:"status" binding:"required"`
+	Reason string `json:"reason" binding:"required,max=255"`
+}
+
+// CloseAccountRequest names where any remaining balance is paid out. It may
+// be omitted when the balance is zero.
+type CloseAccountRequest struct {
+	PayoutAccountNo string `json:"payout_account_no"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type TokenResponse struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
 }
 
 type AccountCreatedResponse struct {
-	Message     string  `json:"message"`
-	AccountID   uint    `json:"account_id"`
-	AccountNo   string  `json:"account_no"`
-	Balance     float64 `json:"balance"`
-	AccountType string  `json:"account_type"`
+	Message     string         `json:"message"`
+	AccountID   uint           `json:"account_id"`
+	AccountNo   string         `json:"account_no"`
+	Balance     money.Amount   `json:"balance" swaggertype:"string" example:"100.00"`
+	Currency    money.Currency `json:"currency" swaggertype:"string" example:"USD"`
+	AccountType string         `json:"account_type"`
 }
 
 type TransactionResponse struct {
-	Message string  `json:"message"`
-	Balance float64 `json:"balance"`
+	Message      string         `json:"message"`
+	Balance      money.Amount   `json:"balance" swaggertype:"string" example:"75.00"`
+	Currency     money.Currency `json:"currency" swaggertype:"string" example:"USD"`
+	FXConversion *FXConversion  `json:"fx_conversion,omitempty"`
+	Fees         []FeeCharge    `json:"fees,omitempty"`
 }
 
+// TransactionRequest is an amount in the currency of the account it is taken
+// from. Currency is optional; when given it must be that currency.
 type TransactionRequest struct {
-	Amount float64 `json:"amount"`
+	Amount   money.Amount `json:"amount" swaggertype:"string" example:"25.00"`
+	Currency string       `json:"currency,omitempty" example:"USD"`
+}
+
+type AccountStatusResponse struct {
+	Message string  `json:"message"`
+	Account Account `json:"account"`
+}
+
+// InterestHistoryResponse shows how an account's interest was worked out.
+// Pending is accrued interest not yet paid in.
+type InterestHistoryResponse struct {
+	AccountNo       string                   `json:"account_no"`
+	Currency        money.Currency           `json:"currency" swaggertype:"string" example:"USD"`
+	Product         string                   `json:"product"`
+	DayCount        string                   `json:"day_count"`
+	InterestTiers   []InterestTier           `json:"interest_tiers"`
+	Pending         money.Micros             `json:"pending" swaggertype:"string" example:"0.273972"`
+	Accruals        []InterestAccrual        `json:"accruals"`
+	Capitalizations []InterestCapitalization `json:"capitalizations"`
+}
+
+// TransferQuoteResponse is what a transfer would cost without making it.
+// Credited, Rate and Spread are in the receiving account's currency.
+type TransferQuoteResponse struct {
+	Amount            money.Amount   `json:"amount" swaggertype:"string" example:"100.00"`
+	Currency          money.Currency `json:"currency" swaggertype:"string" example:"USD"`
+	Fees              []FeeCharge    `json:"fees"`
+	TotalFees         money.Amount   `json:"total_fees" swaggertype:"string" example:"1.50"`
+	TotalDebit        money.Amount   `json:"total_debit" swaggertype:"string" example:"101.50"`
+	Credited          money.Amount   `json:"credited" swaggertype:"string" example:"92.10"`
+	CreditedCurrency  money.Currency `json:"credited_currency" swaggertype:"string" example:"EUR"`
+	Rate              string         `json:"rate,omitempty" example:"0.9256000000"`
+	Spread            money.Amount   `json:"spread,omitempty" swaggertype:"string" example:"0.46"`
+	SufficientBalance bool           `json:"sufficient_balance"`
+}
+
+// FeeWaiverRequest waives fees for either a user or a product. An empty Kind
+// waives every kind of fee.
+type FeeWaiverRequest struct {
+	UserID      *uint      `json:"user_id"`
+	ProductCode string     `json:"product_code"`
+	Kind        string     `json:"kind" example:"monthly_maintenance"`
+	Reason      string     `json:"reason" binding:"required,max=255"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+}
+
+type FeeWaiversResponse struct {
+	Waivers []FeeWaiver `json:"waivers"`
 }
 
 type AccountsResponse struct {
 	Accounts []Account `json:"accounts"`
 }
+
+type LedgerAccountBalance struct {
+	LedgerAccount
+	Balance money.Amount `json:"balance" swaggertype:"string" example:"1250.00"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Session is one login. Every refresh token issued from that login belongs to
+// it, so revoking the session revokes the whole token family. AuthTime and
+// ACR record when and how the user last proved who they are, which is
+// refreshed by re-authenticating without starting a new session.
+type Session struct {
+	gorm.Model
+	UserID        uint `gorm:"index;not null"`
+	AuthTime      *time.Time
+	ACR           string `gorm:"column:acr;size:16"`
+	RevokedAt     *time.Time
+	RevokedReason string
+}
+
+// RefreshToken is stored only as a SHA-256 hash. A token is single-use: it is
+// marked used when rotated, and presenting it again revokes its session.
+type RefreshToken struct {
+	gorm.Model
+	SessionID uint      `gorm:"index;not null"`
+	TokenHash string    `gorm:"size:64;unique_index;not null"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+}
@@ -1,18 +1,24 @@
 package models
 
 import (
+	"bank-app/money"
 	"time"
 
 	"github.com/jinzhu/gorm"
 )
 
 type Transaction struct {
-	gorm.Model      `swaggerignore:"true"`
-	TransactionType string    `json:"transaction_type"` // Deposit, Withdrawal, Transfer
-	Amount          float64   `json:"amount"`
-	AccountID       uint      `json:"account_id"`                // Account that initiated the transaction
-	FromAccountID   *uint     `json:"from_account_id,omitempty"` // For transfers, the originating account
-	ToAccountID     *uint     `json:"to_account_id,omitempty"`   // For transfers, the receiving account
-	Status          string    `json:"status"`                    // Success or failure
-	TransactionDate time.Time `json:"transaction_date"`
+	gorm.Model           `swaggerignore:"true"`
+	TransactionType      string         `json:"transaction_type"` // Deposit, Withdrawal, Transfer, Interest, Fee
+	Amount               money.Amount   `json:"amount" gorm:"column:amount_minor;type:bigint;not null;default:0"`
+	Currency             money.Currency `json:"currency" gorm:"type:char(3);not null;default:'USD'"`
+	AccountID            uint           `json:"account_id"`                                    // Account that initiated the transaction
+	FromAccountID        *uint          `json:"from_account_id,omitempty"`                     // For transfers, the originating account
+	ToAccountID          *uint          `json:"to_account_id,omitempty"`                       // For transfers, the receiving account
+	Status               string         `json:"status"`                                        // Success, failure, or waived for fees
+	JournalEntryID       *uint          `json:"journal_entry_id,omitempty"`                    // Ledger entry that moved the money
+	FXConversionID       *uint          `json:"fx_conversion_id,omitempty"`                    // For cross-currency transfers, the conversion applied
+	FeeKind              string         `json:"fee_kind,omitempty" gorm:"size:32"`             // For fees, which rule charged it
+	RelatedTransactionID *uint          `json:"related_transaction_id,omitempty" gorm:"index"` // For fees, the transaction that triggered it
+	TransactionDate      time.Time      `json:"transaction_date"`
 }
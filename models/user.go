@@ -1,12 +1,22 @@
 package models
 
-import "github.com/jinzhu/gorm"
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
 
 type User struct {
-	gorm.Model `swaggerignore:"true"`
-	FirstName  string    `json:"first_name"`
-	LastName   string    `json:"last_name"`
-	Email      string    `json:"email" gorm:"unique;not null"`
-	Password   string    `json:"password"`
-	Accounts   []Account `json:"accounts"`
+	gorm.Model        `swaggerignore:"true"`
+	FirstName         string     `json:"first_name"`
+	LastName          string     `json:"last_name"`
+	Email             string     `json:"email" gorm:"unique;not null"`
+	Password          string     `json:"-"`
+	Role              string     `json:"role" gorm:"size:16;not null;default:'customer'"`
+	EmailVerifiedAt   *time.Time `json:"email_verified_at"`
+	TOTPSecret        string     `json:"-" gorm:"column:totp_secret;size:64"`
+	TOTPPendingSecret string     `json:"-" gorm:"column:totp_pending_secret;size:64"`
+	TOTPEnabled       bool       `json:"totp_enabled" gorm:"column:totp_enabled;not null;default:false"`
+	TOTPLastStep      int64      `json:"-" gorm:"column:totp_last_step;not null;default:0"`
+	Accounts          []Account  `json:"accounts"`
 }
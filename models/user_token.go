@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// UserToken is a single-use token emailed to a user, such as an email
+// verification or password reset link. Only its SHA-256 hash is stored.
+type UserToken struct {
+	gorm.Model
+	UserID    uint      `gorm:"index;not null"`
+	Purpose   string    `gorm:"size:32;not null"`
+	TokenHash string    `gorm:"size:64;unique_index;not null"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+}
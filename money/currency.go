@@ -0,0 +1,81 @@
+package money
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Currency is an ISO 4217 alphabetic currency code.
+type Currency string
+
+const (
+	USD Currency = "USD"
+	EUR Currency = "EUR"
+	GBP Currency = "GBP"
+	LKR Currency = "LKR"
+	INR Currency = "INR"
+	AUD Currency = "AUD"
+	CAD Currency = "CAD"
+	SGD Currency = "SGD"
+)
+
+// DefaultCurrency is used for accounts opened without an explicit currency.
+const DefaultCurrency = USD
+
+// Only currencies whose minor unit matches Scale are supported, so an
+// Amount means the same thing regardless of the currency next to it.
+var supportedCurrencies = map[Currency]bool{
+	USD: true, EUR: true, GBP: true, LKR: true,
+	INR: true, AUD: true, CAD: true, SGD: true,
+}
+
+// ParseCurrency normalises and validates a currency code.
+func ParseCurrency(code string) (Currency, error) {
+	c := Currency(strings.ToUpper(strings.TrimSpace(code)))
+	if !c.Valid() {
+		return "", fmt.Errorf("unsupported currency %q", code)
+	}
+	return c, nil
+}
+
+func (c Currency) Valid() bool {
+	return supportedCurrencies[c]
+}
+
+// Money pairs an Amount with the currency it is denominated in.
+type Money struct {
+	Amount   Amount   `json:"amount"`
+	Currency Currency `json:"currency"`
+}
+
+func New(amount Amount, currency Currency) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+// Add returns m+o. Both values must share a currency.
+func (m Money) Add(o Money) (Money, error) {
+	if m.Currency != o.Currency {
+		return Money{}, ErrCurrencyMismatch
+	}
+	sum, err := m.Amount.Add(o.Amount)
+	if err != nil {
+		return Money{}, err
+	}
+	return New(sum, m.Currency), nil
+}
+
+// Sub returns m-o. Both values must share a currency.
+func (m Money) Sub(o Money) (Money, error) {
+	if m.Currency != o.Currency {
+		return Money{}, ErrCurrencyMismatch
+	}
+	diff, err := m.Amount.Sub(o.Amount)
+	if err != nil {
+		return Money{}, err
+	}
+	return New(diff, m.Currency), nil
+}
+
+func (m Money) String() string {
+	return fmt.Sprintf("%s %s", m.Amount, m.Currency)
+}
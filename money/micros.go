@@ -0,0 +1,40 @@
+package money
+
+import (
+	"fmt"
+	"strconv"
+)
+
+const microsPerMinor = 10000
+
+// Micros is a value in millionths of a currency unit. Interest accrues in
+// Micros so fractions of a cent are not lost from day to day; it becomes an
+// Amount only when paid. It is encoded in JSON as a decimal string such as
+// "0.027397".
+type Micros int64
+
+func (m Micros) String() string {
+	sign := ""
+	abs := uint64(m)
+	if m < 0 {
+		sign = "-"
+		abs = uint64(-(m + 1)) + 1
+	}
+	return fmt.Sprintf("%s%d.%06d", sign, abs/(unitsPerMajor*microsPerMinor), abs%(unitsPerMajor*microsPerMinor))
+}
+
+// Round returns m in minor units, rounding halves to even.
+func (m Micros) Round() Amount {
+	q, r := int64(m)/microsPerMinor, int64(m)%microsPerMinor
+	if r < 0 {
+		q, r = q-1, r+microsPerMinor
+	}
+	if r > microsPerMinor/2 || (r == microsPerMinor/2 && q%2 != 0) {
+		q++
+	}
+	return Amount(q)
+}
+
+func (m Micros) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(m.String())), nil
+}
@@ -0,0 +1,177 @@
+package money
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Scale is the number of decimal places carried by an Amount.
+const Scale = 2
+
+const unitsPerMajor = 100
+
+var (
+	ErrInvalidAmount    = errors.New("invalid amount")
+	ErrTooManyDecimals  = fmt.Errorf("amount has more than %d decimal places", Scale)
+	ErrOverflow         = errors.New("amount overflow")
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+)
+
+// Amount is a monetary value held as an integer number of minor units
+// (cents). It is stored as a BIGINT and encoded in JSON as a decimal string
+// such as "12.34".
+type Amount int64
+
+// FromMinor builds an Amount from a count of minor units.
+func FromMinor(units int64) Amount {
+	return Amount(units)
+}
+
+// Parse reads a decimal string such as "12.34" or "-5". It rejects values
+// with more than Scale decimal places instead of rounding them.
+func Parse(s string) (Amount, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, ErrInvalidAmount
+	}
+
+	negative := false
+	switch s[0] {
+	case '-':
+		negative = true
+		s = s[1:]
+	case '+':
+		s = s[1:]
+	}
+
+	whole, frac, hasDot := strings.Cut(s, ".")
+	if whole == "" || (hasDot && frac == "") || !isDigits(whole) || !isDigits(frac) {
+		return 0, ErrInvalidAmount
+	}
+
+	// Trailing zeros beyond the scale carry no value and are allowed
+	frac = strings.TrimRight(frac, "0")
+	if len(frac) > Scale {
+		return 0, ErrTooManyDecimals
+	}
+	frac += strings.Repeat("0", Scale-len(frac))
+
+	units, err := strconv.ParseInt(whole+frac, 10, 64)
+	if err != nil {
+		return 0, ErrOverflow
+	}
+	if negative {
+		units = -units
+	}
+	return Amount(units), nil
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Minor returns the amount as a count of minor units.
+func (a Amount) Minor() int64 {
+	return int64(a)
+}
+
+func (a Amount) String() string {
+	units := int64(a)
+	sign := ""
+	if units < 0 {
+		sign = "-"
+	}
+	abs := uint64(units)
+	if units < 0 {
+		abs = uint64(-(units + 1)) + 1
+	}
+	return fmt.Sprintf("%s%d.%0*d", sign, abs/unitsPerMajor, Scale, abs%unitsPerMajor)
+}
+
+func (a Amount) IsZero() bool     { return a == 0 }
+func (a Amount) IsPositive() bool { return a > 0 }
+func (a Amount) IsNegative() bool { return a < 0 }
+
+// Add returns a+b, or ErrOverflow if the result does not fit in an int64.
+func (a Amount) Add(b Amount) (Amount, error) {
+	if (b > 0 && a > math.MaxInt64-b) || (b < 0 && a < math.MinInt64-b) {
+		return 0, ErrOverflow
+	}
+	return a + b, nil
+}
+
+// Sub returns a-b, or ErrOverflow if the result does not fit in an int64.
+func (a Amount) Sub(b Amount) (Amount, error) {
+	if (b < 0 && a > math.MaxInt64+b) || (b > 0 && a < math.MinInt64+b) {
+		return 0, ErrOverflow
+	}
+	return a - b, nil
+}
+
+// Neg returns -a.
+func (a Amount) Neg() Amount {
+	return -a
+}
+
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(a.String())), nil
+}
+
+// UnmarshalJSON accepts either a decimal string or a bare JSON number. Bare
+// numbers are parsed from their literal text, never through a float64.
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	text := string(data)
+	if text == "null" {
+		return nil
+	}
+	if unquoted, err := strconv.Unquote(text); err == nil {
+		text = unquoted
+	} else if strings.ContainsAny(text, "eE") {
+		return ErrInvalidAmount
+	}
+
+	parsed, err := Parse(text)
+	if err != nil {
+		return err
+	}
+	*a = parsed
+	return nil
+}
+
+// Value stores the amount as its integer minor units.
+func (a Amount) Value() (driver.Value, error) {
+	return int64(a), nil
+}
+
+func (a *Amount) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*a = 0
+	case int64:
+		*a = Amount(v)
+	case []byte:
+		units, err := strconv.ParseInt(string(v), 10, 64)
+		if err != nil {
+			return fmt.Errorf("money: cannot scan %q into Amount", v)
+		}
+		*a = Amount(units)
+	case string:
+		units, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return fmt.Errorf("money: cannot scan %q into Amount", v)
+		}
+		*a = Amount(units)
+	default:
+		return fmt.Errorf("money: cannot scan %T into Amount", src)
+	}
+	return nil
+}
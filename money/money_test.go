@@ -0,0 +1,235 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		in   string
+		want Amount
+		err  error
+	}{
+		{"12.34", 1234, nil},
+		{"12.3", 1230, nil},
+		{"12", 1200, nil},
+		{"0.01", 1, nil},
+		{"0", 0, nil},
+		{"-5", -500, nil},
+		{"+5.50", 550, nil},
+		{" 7.25 ", 725, nil},
+		{"1.230000", 123, nil},
+		{"92233720368547758.07", math.MaxInt64, nil},
+
+		{"1.234", 0, ErrTooManyDecimals},
+		{"0.001", 0, ErrTooManyDecimals},
+		{"92233720368547758.08", 0, ErrOverflow},
+
+		{"", 0, ErrInvalidAmount},
+		{"1.", 0, ErrInvalidAmount},
+		{".5", 0, ErrInvalidAmount},
+		{"-", 0, ErrInvalidAmount},
+		{"+", 0, ErrInvalidAmount},
+		{"--1", 0, ErrInvalidAmount},
+		{"+-1", 0, ErrInvalidAmount},
+		{"1-", 0, ErrInvalidAmount},
+		{"1.-5", 0, ErrInvalidAmount},
+		{"1.2.3", 0, ErrInvalidAmount},
+		{"1,000.00", 0, ErrInvalidAmount},
+		{"1e3", 0, ErrInvalidAmount},
+		{"abc", 0, ErrInvalidAmount},
+	}
+	for _, tc := range cases {
+		got, err := Parse(tc.in)
+		if !errors.Is(err, tc.err) || got != tc.want {
+			t.Errorf("Parse(%q) = %d, %v; want %d, %v", tc.in, got, err, tc.want, tc.err)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	cases := []struct {
+		in   Amount
+		want string
+	}{
+		{0, "0.00"},
+		{1, "0.01"},
+		{1234, "12.34"},
+		{-5, "-0.05"},
+		{-1234, "-12.34"},
+		{math.MaxInt64, "92233720368547758.07"},
+		{math.MinInt64, "-92233720368547758.08"},
+	}
+	for _, tc := range cases {
+		if got := tc.in.String(); got != tc.want {
+			t.Errorf("Amount(%d).String() = %q, want %q", int64(tc.in), got, tc.want)
+		}
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	for _, amount := range []Amount{0, 1, 1234, -1234, math.MaxInt64} {
+		data, err := json.Marshal(amount)
+		if err != nil {
+			t.Fatalf("marshalling %d: %v", int64(amount), err)
+		}
+		if data[0] != '"' {
+			t.Errorf("Amount %d encoded as %s, want a JSON string", int64(amount), data)
+		}
+		var back Amount
+		if err := json.Unmarshal(data, &back); err != nil || back != amount {
+			t.Errorf("round trip of %d through %s = %d, %v", int64(amount), data, int64(back), err)
+		}
+	}
+}
+
+func TestUnmarshalJSON(t *testing.T) {
+	cases := []struct {
+		in      string
+		want    Amount
+		wantErr bool
+	}{
+		{`"12.34"`, 1234, false},
+		{`12.34`, 1234, false},
+		{`100`, 10000, false},
+		{`null`, 0, false},
+		{`"12.345"`, 0, true},
+		{`12.345`, 0, true},
+		{`1e2`, 0, true},
+		{`"1."`, 0, true},
+		{`true`, 0, true},
+	}
+	for _, tc := range cases {
+		var got Amount
+		err := json.Unmarshal([]byte(tc.in), &got)
+		if (err != nil) != tc.wantErr || got != tc.want {
+			t.Errorf("Unmarshal(%s) = %d, %v; want %d, error %v", tc.in, int64(got), err, int64(tc.want), tc.wantErr)
+		}
+	}
+}
+
+func TestScan(t *testing.T) {
+	cases := []struct {
+		src     interface{}
+		want    Amount
+		wantErr bool
+	}{
+		{int64(1234), 1234, false},
+		{int64(-5), -5, false},
+		{[]byte("1234"), 1234, false},
+		{[]byte("-99"), -99, false},
+		{"1234", 1234, false},
+		{nil, 0, false},
+		{[]byte("12.34"), 0, true},
+		{"abc", 0, true},
+		{12.34, 0, true},
+	}
+	for _, tc := range cases {
+		got := Amount(777)
+		err := got.Scan(tc.src)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("Scan(%#v) = %d, want an error", tc.src, int64(got))
+			}
+			continue
+		}
+		if err != nil || got != tc.want {
+			t.Errorf("Scan(%#v) = %d, %v; want %d", tc.src, int64(got), err, int64(tc.want))
+		}
+	}
+
+	value, err := Amount(1234).Value()
+	if err != nil || value != int64(1234) {
+		t.Errorf("Value() = %#v, %v; want int64(1234)", value, err)
+	}
+}
+
+func TestAddSub(t *testing.T) {
+	cases := []struct {
+		name   string
+		a, b   Amount
+		add    Amount
+		addErr error
+		sub    Amount
+		subErr error
+	}{
+		{"small", 150, 25, 175, nil, 125, nil},
+		{"negative", -150, 25, -125, nil, -175, nil},
+		{"max plus one", math.MaxInt64, 1, 0, ErrOverflow, math.MaxInt64 - 1, nil},
+		{"min minus one", math.MinInt64, -1, 0, ErrOverflow, math.MinInt64 + 1, nil},
+		{"min plus min", math.MinInt64, math.MinInt64, 0, ErrOverflow, 0, nil},
+		{"max minus min", math.MaxInt64, math.MinInt64, -1, nil, 0, ErrOverflow},
+		{"zero minus min", 0, math.MinInt64, math.MinInt64, nil, 0, ErrOverflow},
+		{"min minus one positive", math.MinInt64, 1, math.MinInt64 + 1, nil, 0, ErrOverflow},
+	}
+	for _, tc := range cases {
+		sum, err := tc.a.Add(tc.b)
+		if !errors.Is(err, tc.addErr) || (err == nil && sum != tc.add) {
+			t.Errorf("%s: %d + %d = %d, %v; want %d, %v", tc.name, int64(tc.a), int64(tc.b), int64(sum), err, int64(tc.add), tc.addErr)
+		}
+		diff, err := tc.a.Sub(tc.b)
+		if !errors.Is(err, tc.subErr) || (err == nil && diff != tc.sub) {
+			t.Errorf("%s: %d - %d = %d, %v; want %d, %v", tc.name, int64(tc.a), int64(tc.b), int64(diff), err, int64(tc.sub), tc.subErr)
+		}
+	}
+}
+
+func TestMoneyCurrencyMismatch(t *testing.T) {
+	if _, err := New(100, USD).Add(New(100, EUR)); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("USD + EUR: got %v, want ErrCurrencyMismatch", err)
+	}
+	if _, err := New(100, USD).Sub(New(100, EUR)); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("USD - EUR: got %v, want ErrCurrencyMismatch", err)
+	}
+	if sum, err := New(100, USD).Add(New(50, USD)); err != nil || sum != New(150, USD) {
+		t.Errorf("USD + USD = %v, %v; want 1.50 USD", sum, err)
+	}
+}
+
+func TestMicrosRound(t *testing.T) {
+	cases := []struct {
+		in   Micros
+		want Amount
+	}{
+		{0, 0},
+		{4999, 0},
+		{5000, 0},
+		{5001, 1},
+		{15000, 2},
+		{25000, 2},
+		{25001, 3},
+		{35000, 4},
+		{9999, 1},
+		{-4999, 0},
+		{-5000, 0},
+		{-5001, -1},
+		{-15000, -2},
+		{-25000, -2},
+		{-35000, -4},
+	}
+	for _, tc := range cases {
+		if got := tc.in.Round(); got != tc.want {
+			t.Errorf("Micros(%d).Round() = %d, want %d", int64(tc.in), int64(got), int64(tc.want))
+		}
+	}
+}
+
+func TestMicrosString(t *testing.T) {
+	cases := []struct {
+		in   Micros
+		want string
+	}{
+		{0, "0.000000"},
+		{27397, "0.027397"},
+		{1000000, "1.000000"},
+		{-1, "-0.000001"},
+	}
+	for _, tc := range cases {
+		if got := tc.in.String(); got != tc.want {
+			t.Errorf("Micros(%d).String() = %q, want %q", int64(tc.in), got, tc.want)
+		}
+	}
+}
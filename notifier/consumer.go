@@ -0,0 +1,108 @@
+package notifier
+
+import (
+	"bank-app/events"
+	"bank-app/rabbitmq"
+	"context"
+	"encoding/json"
+	"log"
+
+	"github.com/streadway/amqp"
+)
+
+// Consumer turns events from a queue into emails.
+//
+// A message that cannot be decoded or rendered is poison: retrying will not
+// help, so it is parked in the queue's dead queue straight away. A message
+// whose delivery fails is rejected and comes back through the retry queue
+// after the topology's retry delay, until it has been retried MaxRetries
+// times, after which it is parked as well.
+type Consumer struct {
+	Conn       *rabbitmq.Connection
+	Topology   rabbitmq.Topology
+	Queue      rabbitmq.Queue
+	Sender     Sender
+	MaxRetries int
+	Prefetch   int
+}
+
+// Run consumes until ctx is cancelled.
+func (c *Consumer) Run(ctx context.Context) {
+	c.Conn.Consume(ctx, c.Queue.Name, c.Prefetch, c.handle)
+}
+
+func (c *Consumer) handle(delivery amqp.Delivery) {
+	var envelope events.Envelope
+	if err := json.Unmarshal(delivery.Body, &envelope); err != nil {
+		c.park(delivery, "undecodable message: "+err.Error())
+		return
+	}
+
+	message, err := Render(envelope)
+	if err != nil {
+		c.park(delivery, err.Error())
+		return
+	}
+
+	if err := c.Sender.Send(message); err != nil {
+		retries := retryCount(delivery, c.Queue.Name)
+		if retries >= c.MaxRetries {
+			c.park(delivery, "delivery failed after retries: "+err.Error())
+			return
+		}
+		log.Printf("Failed to send %s email for event %s (retry %d of %d): %v\n",
+			envelope.Type, envelope.EventID, retries+1, c.MaxRetries, err)
+		delivery.Nack(false, false)
+		return
+	}
+
+	delivery.Ack(false)
+}
+
+// park moves a delivery to the dead queue and acknowledges it. If parking
+// fails the delivery is requeued so it is not lost.
+func (c *Consumer) park(delivery amqp.Delivery, reason string) {
+	headers := amqp.Table{}
+	for k, v := range delivery.Headers {
+		headers[k] = v
+	}
+	headers["x-parked-reason"] = reason
+
+	err := c.Conn.Publish(c.Topology.DeadLetterExchange, c.Queue.Name, amqp.Publishing{
+		Headers:       headers,
+		ContentType:   delivery.ContentType,
+		DeliveryMode:  amqp.Persistent,
+		MessageId:     delivery.MessageId,
+		CorrelationId: delivery.CorrelationId,
+		Type:          delivery.Type,
+		Timestamp:     delivery.Timestamp,
+		Body:          delivery.Body,
+	})
+	if err != nil {
+		log.Printf("Failed to park message %s: %v\n", delivery.MessageId, err)
+		delivery.Nack(false, true)
+		return
+	}
+
+	log.Printf("Parked message %s in %s: %s\n", delivery.MessageId, c.Queue.DeadQueue(), reason)
+	delivery.Ack(false)
+}
+
+// retryCount reads how many times the broker has dead-lettered this message
+// out of queue, from the x-death header.
+func retryCount(delivery amqp.Delivery, queue string) int {
+	deaths, ok := delivery.Headers["x-death"].([]interface{})
+	if !ok {
+		return 0
+	}
+	for _, d := range deaths {
+		death, ok := d.(amqp.Table)
+		if !ok || death["queue"] != queue || death["reason"] != "rejected" {
+			continue
+		}
+		if count, ok := death["count"].(int64); ok {
+			return int(count)
+		}
+	}
+	return 0
+}
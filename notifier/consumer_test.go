@@ -0,0 +1,46 @@
+package notifier
+
+import (
+	"testing"
+
+	"github.com/streadway/amqp"
+)
+
+func TestRetryCount(t *testing.T) {
+	death := func(queue, reason string, count interface{}) amqp.Table {
+		return amqp.Table{"queue": queue, "reason": reason, "count": count}
+	}
+
+	cases := []struct {
+		name    string
+		headers amqp.Table
+		want    int
+	}{
+		{"no headers", nil, 0},
+		{"no x-death", amqp.Table{"other": "value"}, 0},
+		{"rejected from the queue", amqp.Table{"x-death": []interface{}{
+			death("bank.notifications", "rejected", int64(3)),
+		}}, 3},
+		// The retry queue's own expiry entries are not rejections
+		{"expired from the retry queue", amqp.Table{"x-death": []interface{}{
+			death("bank.notifications.retry", "expired", int64(4)),
+			death("bank.notifications", "rejected", int64(4)),
+		}}, 4},
+		{"another queue", amqp.Table{"x-death": []interface{}{
+			death("bank.audit", "rejected", int64(7)),
+		}}, 0},
+		{"expired, not rejected", amqp.Table{"x-death": []interface{}{
+			death("bank.notifications", "expired", int64(2)),
+		}}, 0},
+		{"count of the wrong type", amqp.Table{"x-death": []interface{}{
+			death("bank.notifications", "rejected", "3"),
+		}}, 0},
+		{"malformed x-death", amqp.Table{"x-death": "rejected"}, 0},
+		{"malformed entry", amqp.Table{"x-death": []interface{}{"rejected"}}, 0},
+	}
+	for _, tc := range cases {
+		if got := retryCount(amqp.Delivery{Headers: tc.headers}, "bank.notifications"); got != tc.want {
+			t.Errorf("%s: retryCount = %d, want %d", tc.name, got, tc.want)
+		}
+	}
+}
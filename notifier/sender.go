@@ -0,0 +1,112 @@
+package notifier
+
+import (
+	"bank-app/events"
+	"bytes"
+	"fmt"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Sender delivers a rendered email.
+type Sender interface {
+	Send(message Message) error
+}
+
+// LoadSender builds the sender selected by MAIL_SENDER:
+//
+//	smtp    - relay through SMTP_HOST:SMTP_PORT, authenticating with
+//	          SMTP_USERNAME/SMTP_PASSWORD when set
+//	maildir - write messages into the Maildir at MAILDIR_PATH (the default)
+//
+// Messages are sent from MAIL_FROM.
+func LoadSender() (Sender, error) {
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = "no-reply@bank.local"
+	}
+
+	switch kind := os.Getenv("MAIL_SENDER"); kind {
+	case "smtp":
+		port := os.Getenv("SMTP_PORT")
+		if port == "" {
+			port = "587"
+		}
+		return &SMTPSender{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     port,
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     from,
+		}, nil
+	case "", "maildir":
+		path := os.Getenv("MAILDIR_PATH")
+		if path == "" {
+			path = "maildir"
+		}
+		return NewMaildirSender(path, from)
+	default:
+		return nil, fmt.Errorf("unknown MAIL_SENDER %q", kind)
+	}
+}
+
+// SMTPSender delivers mail through an SMTP relay.
+type SMTPSender struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+func (s *SMTPSender) Send(message Message) error {
+	var auth smtp.Auth
+	if s.Username != "" {
+		auth = smtp.PlainAuth("", s.Username, s.Password, s.Host)
+	}
+	return smtp.SendMail(net.JoinHostPort(s.Host, s.Port), auth, s.From, []string{message.To}, format(s.From, message))
+}
+
+// MaildirSender writes each email into a Maildir so it can be read with any
+// mail client during development.
+type MaildirSender struct {
+	Dir  string
+	From string
+}
+
+func NewMaildirSender(dir, from string) (*MaildirSender, error) {
+	for _, sub := range []string{"tmp", "new", "cur"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			return nil, err
+		}
+	}
+	return &MaildirSender{Dir: dir, From: from}, nil
+}
+
+func (s *MaildirSender) Send(message Message) error {
+	hostname, _ := os.Hostname()
+	name := fmt.Sprintf("%d.%s.%s", time.Now().UnixNano(), events.NewID(), hostname)
+
+	// Maildir delivery: write to tmp, then move into new in one step
+	tmpPath := filepath.Join(s.Dir, "tmp", name)
+	if err := os.WriteFile(tmpPath, format(s.From, message), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, filepath.Join(s.Dir, "new", name))
+}
+
+func format(from string, message Message) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", message.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", message.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(message.Body, "\n", "\r\n"))
+	return b.Bytes()
+}
@@ -0,0 +1,135 @@
+package notifier
+
+import (
+	"bank-app/events"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"text/template"
+)
+
+// Message is a rendered email ready to hand to a Sender.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+var ErrNoTemplate = errors.New("notifier: no template for event")
+
+type emailTemplate struct {
+	subject *template.Template
+	body    *template.Template
+}
+
+func newTemplate(subject, body string) emailTemplate {
+	return emailTemplate{
+		subject: template.Must(template.New("subject").Parse(subject)),
+		body:    template.Must(template.New("body").Parse(body)),
+	}
+}
+
+// templates are keyed by event type. Each is executed with the decoded event
+// as its data.
+var templates = map[string]emailTemplate{
+	events.TypeDepositSucceeded: newTemplate(
+		"Deposit of {{.Amount}} {{.Currency}} received",
+		`Hello,
+
+{{.Amount}} {{.Currency}} was deposited into your account {{.AccountNo}}.
+Your new balance is {{.Balance}} {{.Currency}}.
+
+Transaction reference: {{.TransactionID}}
+`),
+	events.TypeWithdrawalSucceeded: newTemplate(
+		"Withdrawal of {{.Amount}} {{.Currency}}",
+		`Hello,
+
+{{.Amount}} {{.Currency}} was withdrawn from your account {{.AccountNo}}.
+Your new balance is {{.Balance}} {{.Currency}}.
+
+If you did not make this withdrawal, contact us immediately.
+
+Transaction reference: {{.TransactionID}}
+`),
+	events.TypeTransferSent: newTemplate(
+		"You sent {{.Amount}} {{.Currency}}",
+		`Hello,
+
+You transferred {{.Amount}} {{.Currency}} from your account {{.FromAccountNo}} to account {{.ToAccountNo}}.
+
+If you did not make this transfer, contact us immediately.
+
+Transaction reference: {{.TransactionID}}
+`),
+	events.TypeTransferReceived: newTemplate(
+		"You received {{.Amount}} {{.Currency}}",
+		`Hello,
+
+Account {{.FromAccountNo}} transferred {{.Amount}} {{.Currency}} to your account {{.ToAccountNo}}.
+
+Transaction reference: {{.TransactionID}}
+`),
+	events.TypeAccountLocked: newTemplate(
+		"Your account has been locked",
+		`Hello,
+
+After {{.Failures}} failed sign-in attempts, the last from {{.IPAddress}}, sign-in to your account is blocked until {{.LockedUntil.Format "2006-01-02 15:04 MST"}}.
+
+If these attempts were not you, consider changing your password once you can sign in again. If you need access sooner, contact support.
+`),
+	events.TypeEmailVerificationRequested: newTemplate(
+		"Verify your email address",
+		`Hello {{.FirstName}},
+
+Please confirm this is your email address by opening the link below:
+
+{{.Link}}
+
+The link expires at {{.ExpiresAt.Format "2006-01-02 15:04 MST"}}. You will not be able to move money until your address is verified.
+`),
+	events.TypePasswordResetRequested: newTemplate(
+		"Reset your password",
+		`Hello {{.FirstName}},
+
+We received a request to reset your password. Open the link below to choose a new one:
+
+{{.Link}}
+
+The link can be used once and expires at {{.ExpiresAt.Format "2006-01-02 15:04 MST"}}. If you did not ask for this, you can ignore this email.
+`),
+}
+
+// Render builds the email for an event envelope.
+func Render(envelope events.Envelope) (Message, error) {
+	tmpl, ok := templates[envelope.Type]
+	if !ok {
+		return Message{}, fmt.Errorf("%w %s", ErrNoTemplate, envelope.Type)
+	}
+
+	event, err := envelope.Decode()
+	if err != nil {
+		return Message{}, err
+	}
+
+	var recipient struct {
+		ToEmail string `json:"to_email"`
+	}
+	if err := json.Unmarshal(envelope.Data, &recipient); err != nil {
+		return Message{}, err
+	}
+	if recipient.ToEmail == "" {
+		return Message{}, fmt.Errorf("notifier: %s event has no recipient", envelope.Type)
+	}
+
+	var subject, body bytes.Buffer
+	if err := tmpl.subject.Execute(&subject, event); err != nil {
+		return Message{}, err
+	}
+	if err := tmpl.body.Execute(&body, event); err != nil {
+		return Message{}, err
+	}
+
+	return Message{To: recipient.ToEmail, Subject: subject.String(), Body: body.String()}, nil
+}
@@ -0,0 +1,78 @@
+package notifier
+
+import (
+	"bank-app/events"
+	"bank-app/money"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func envelope(t *testing.T, event events.Event) events.Envelope {
+	t.Helper()
+	e, err := events.New(event, "notifier-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func TestRender(t *testing.T) {
+	expires := time.Date(2025, 6, 1, 14, 30, 0, 0, time.UTC)
+	cases := []struct {
+		name    string
+		event   events.Event
+		to      string
+		subject string
+		body    []string
+	}{
+		{
+			"deposit",
+			&events.DepositSucceeded{TransactionID: 42, AccountNo: "000000123", Amount: 1234, Currency: money.USD, Balance: 10000, ToEmail: "ana@example.com"},
+			"ana@example.com",
+			"Deposit of 12.34 USD received",
+			[]string{"12.34 USD was deposited into your account 000000123", "new balance is 100.00 USD", "reference: 42"},
+		},
+		{
+			"password reset",
+			&events.PasswordResetRequested{FirstName: "Ana", Link: "https://bank.example/reset-password?token=abc", ExpiresAt: expires, ToEmail: "ana@example.com"},
+			"ana@example.com",
+			"Reset your password",
+			[]string{"Hello Ana,", "https://bank.example/reset-password?token=abc", "expires at 2025-06-01 14:30 UTC"},
+		},
+	}
+	for _, tc := range cases {
+		message, err := Render(envelope(t, tc.event))
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if message.To != tc.to || message.Subject != tc.subject {
+			t.Errorf("%s: to %q, subject %q; want %q, %q", tc.name, message.To, message.Subject, tc.to, tc.subject)
+		}
+		for _, want := range tc.body {
+			if !strings.Contains(message.Body, want) {
+				t.Errorf("%s: body does not contain %q:\n%s", tc.name, want, message.Body)
+			}
+		}
+	}
+}
+
+func TestRenderRejects(t *testing.T) {
+	// Lockouts of an IP address are for operators, not customers
+	if _, err := Render(envelope(t, &events.IPLocked{IPAddress: "192.0.2.1"})); !errors.Is(err, ErrNoTemplate) {
+		t.Errorf("event without a template: got %v, want ErrNoTemplate", err)
+	}
+
+	if _, err := Render(envelope(t, &events.DepositSucceeded{Amount: 100, Currency: money.USD})); err == nil {
+		t.Error("event without a recipient rendered")
+	}
+
+	broken := envelope(t, &events.DepositSucceeded{ToEmail: "ana@example.com"})
+	broken.Data = json.RawMessage(`{"amount": true, "to_email": "ana@example.com"}`)
+	if _, err := Render(broken); err == nil {
+		t.Error("undecodable event rendered")
+	}
+}
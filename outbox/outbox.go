@@ -0,0 +1,195 @@
+package outbox
+
+import (
+	"bank-app/events"
+	"bank-app/models"
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	StatusPending = "pending"
+	StatusSent    = "sent"
+	// Given up on after MaxAttempts failures, or unreadable; kept for an
+	// operator to inspect
+	StatusDead = "dead"
+)
+
+// Enqueue stores envelope for publishing once tx commits. It must be called
+// with the same transaction that makes the change the event describes.
+func Enqueue(tx *gorm.DB, envelope events.Envelope) error {
+	payload, err := json.Marshal(envelope)
+	if err != nil {
+		return err
+	}
+
+	return tx.Create(&models.OutboxEvent{
+		Payload:       string(payload),
+		Status:        StatusPending,
+		NextAttemptAt: time.Now(),
+	}).Error
+}
+
+// Relay publishes pending outbox events and marks them sent. Failed
+// publishes are retried with exponential backoff, so every event is
+// delivered at least once unless it fails MaxAttempts times and is
+// dead-lettered. Several relays, one per API replica, can share an outbox:
+// each event is claimed by one of them before it is published.
+type Relay struct {
+	DB           *gorm.DB
+	Publisher    events.EventPublisher
+	PollInterval time.Duration
+	BatchSize    int
+	MaxBackoff   time.Duration
+	MaxAttempts  int
+	// How long a claim keeps other relays off an event; a relay that dies
+	// mid-publish leaves the event to be retried once it runs out
+	ClaimTimeout time.Duration
+}
+
+func NewRelay(db *gorm.DB, publisher events.EventPublisher) *Relay {
+	return &Relay{
+		DB:           db,
+		Publisher:    publisher,
+		PollInterval: time.Second,
+		BatchSize:    100,
+		MaxBackoff:   5 * time.Minute,
+		MaxAttempts:  20,
+		ClaimTimeout: time.Minute,
+	}
+}
+
+// Run polls the outbox until ctx is cancelled.
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.RelayPending(); err != nil {
+			log.Printf("Outbox relay failed: %v\n", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RelayPending publishes one batch of events that are due.
+func (r *Relay) RelayPending() error {
+	var pending []models.OutboxEvent
+	err := r.DB.Where("status = ? AND next_attempt_at <= ?", StatusPending, time.Now()).
+		Order("id").
+		Limit(r.BatchSize).
+		Find(&pending).Error
+	if err != nil {
+		return err
+	}
+
+	for _, event := range pending {
+		claimed, err := r.claim(event)
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			continue
+		}
+
+		var envelope events.Envelope
+		if err := json.Unmarshal([]byte(event.Payload), &envelope); err != nil {
+			// Retrying cannot fix the payload
+			r.markDead(event, err)
+			continue
+		}
+		if err := r.Publisher.Publish(envelope); err != nil {
+			r.markFailed(event, err)
+			continue
+		}
+
+		now := time.Now()
+		if err := r.DB.Model(&event).Updates(map[string]interface{}{
+			"status":   StatusSent,
+			"attempts": event.Attempts + 1,
+			"sent_at":  &now,
+		}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// claim takes event for this relay by pushing its next attempt past the
+// claim timeout, unless another relay got there first.
+func (r *Relay) claim(event models.OutboxEvent) (bool, error) {
+	now := time.Now()
+	result := r.DB.Model(&models.OutboxEvent{}).
+		Where("id = ? AND status = ? AND next_attempt_at <= ?", event.ID, StatusPending, now).
+		UpdateColumn("next_attempt_at", now.Add(r.ClaimTimeout))
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *Relay) markFailed(event models.OutboxEvent, publishErr error) {
+	attempts := event.Attempts + 1
+	if attempts >= r.MaxAttempts {
+		r.markDead(event, publishErr)
+		return
+	}
+	backoff := r.PollInterval << uint(min(attempts, 20))
+	if backoff > r.MaxBackoff || backoff <= 0 {
+		backoff = r.MaxBackoff
+	}
+
+	log.Printf("Failed to publish outbox event %d (attempt %d): %v\n", event.ID, attempts, publishErr)
+	if err := r.DB.Model(&event).Updates(map[string]interface{}{
+		"attempts":        attempts,
+		"next_attempt_at": time.Now().Add(backoff),
+		"last_error":      publishErr.Error(),
+	}).Error; err != nil {
+		log.Printf("Failed to record outbox failure for event %d: %v\n", event.ID, err)
+	}
+}
+
+func (r *Relay) markDead(event models.OutboxEvent, cause error) {
+	log.Printf("Giving up on outbox event %d after %d attempts: %v\n", event.ID, event.Attempts+1, cause)
+	if err := r.DB.Model(&event).Updates(map[string]interface{}{
+		"status":     StatusDead,
+		"attempts":   event.Attempts + 1,
+		"last_error": cause.Error(),
+	}).Error; err != nil {
+		log.Printf("Failed to dead-letter outbox event %d: %v\n", event.ID, err)
+	}
+}
+
+// PurgeSent deletes events sent before cutoff. Pending and dead events are
+// kept.
+func PurgeSent(db *gorm.DB, cutoff time.Time) (int64, error) {
+	result := db.Unscoped().Where("status = ? AND sent_at < ?", StatusSent, cutoff).Delete(&models.OutboxEvent{})
+	return result.RowsAffected, result.Error
+}
+
+// Sweep purges events sent more than retention ago every interval until ctx
+// is cancelled, so the outbox does not grow without bound.
+func Sweep(ctx context.Context, db *gorm.DB, retention, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if n, err := PurgeSent(db, time.Now().Add(-retention)); err != nil {
+			log.Printf("Outbox sweep failed: %v\n", err)
+		} else if n > 0 {
+			log.Printf("Deleted %d sent outbox events\n", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
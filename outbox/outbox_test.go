@@ -0,0 +1,218 @@
+package outbox
+
+import (
+	"bank-app/events"
+	"bank-app/models"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/mysql"
+)
+
+// openTestDB connects to the MySQL database in TEST_MYSQL_DSN with an empty
+// outbox, or skips the test when none is configured.
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_MYSQL_DSN")
+	if dsn == "" {
+		t.Skip("TEST_MYSQL_DSN is not set, e.g. user:pass@tcp(localhost:3306)/bank_test?charset=utf8mb4&parseTime=True&loc=Local")
+	}
+
+	db, err := gorm.Open("mysql", dsn)
+	if err != nil {
+		t.Fatalf("connecting to test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if err := db.AutoMigrate(&models.OutboxEvent{}).Error; err != nil {
+		t.Fatalf("migrating test database: %v", err)
+	}
+	if err := db.Unscoped().Delete(&models.OutboxEvent{}).Error; err != nil {
+		t.Fatalf("emptying outbox: %v", err)
+	}
+	return db
+}
+
+func enqueue(t *testing.T, db *gorm.DB, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		envelope, err := events.New(&events.IPLocked{
+			IPAddress:   "192.0.2.1",
+			Failures:    i + 1,
+			LockedUntil: time.Now().UTC(),
+		}, "outbox-test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := Enqueue(db, envelope); err != nil {
+			t.Fatalf("enqueueing: %v", err)
+		}
+	}
+}
+
+func statuses(t *testing.T, db *gorm.DB) map[string]int {
+	t.Helper()
+	var rows []models.OutboxEvent
+	if err := db.Find(&rows).Error; err != nil {
+		t.Fatal(err)
+	}
+	counts := map[string]int{}
+	for _, row := range rows {
+		counts[row.Status]++
+	}
+	return counts
+}
+
+// makeDue lets the relay retry failed events straight away.
+func makeDue(t *testing.T, db *gorm.DB) {
+	t.Helper()
+	if err := db.Model(&models.OutboxEvent{}).UpdateColumn("next_attempt_at", time.Now().Add(-time.Second)).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+type failingPublisher struct{}
+
+func (failingPublisher) Publish(events.Envelope) error { return errors.New("broker unavailable") }
+func (failingPublisher) Close() error                  { return nil }
+
+func TestRelayPublishesPendingEvents(t *testing.T) {
+	db := openTestDB(t)
+	enqueue(t, db, 3)
+
+	publisher := events.NewMemoryPublisher()
+	if err := NewRelay(db, publisher).RelayPending(); err != nil {
+		t.Fatalf("relaying: %v", err)
+	}
+
+	if got := len(publisher.Published()); got != 3 {
+		t.Errorf("published %d events, want 3", got)
+	}
+	if got := statuses(t, db); got[StatusSent] != 3 || len(got) != 1 {
+		t.Errorf("statuses = %v, want 3 sent", got)
+	}
+
+	// Sent events are not published again
+	if err := NewRelay(db, publisher).RelayPending(); err != nil {
+		t.Fatalf("relaying: %v", err)
+	}
+	if got := len(publisher.Published()); got != 3 {
+		t.Errorf("published %d events after a second run, want 3", got)
+	}
+}
+
+func TestRelayRetriesThenDeadLetters(t *testing.T) {
+	db := openTestDB(t)
+	enqueue(t, db, 1)
+
+	relay := NewRelay(db, failingPublisher{})
+	relay.MaxAttempts = 3
+	for attempt := 1; attempt < relay.MaxAttempts; attempt++ {
+		if err := relay.RelayPending(); err != nil {
+			t.Fatalf("relaying: %v", err)
+		}
+		var event models.OutboxEvent
+		if err := db.First(&event).Error; err != nil {
+			t.Fatal(err)
+		}
+		if event.Status != StatusPending || event.Attempts != attempt || !event.NextAttemptAt.After(time.Now()) {
+			t.Fatalf("after attempt %d: status %s, %d attempts, next at %s; want pending and backing off",
+				attempt, event.Status, event.Attempts, event.NextAttemptAt)
+		}
+		makeDue(t, db)
+	}
+
+	if err := relay.RelayPending(); err != nil {
+		t.Fatalf("relaying: %v", err)
+	}
+	if got := statuses(t, db); got[StatusDead] != 1 {
+		t.Errorf("statuses = %v, want the event dead-lettered", got)
+	}
+
+	// A working publisher no longer picks it up
+	publisher := events.NewMemoryPublisher()
+	makeDue(t, db)
+	if err := NewRelay(db, publisher).RelayPending(); err != nil {
+		t.Fatalf("relaying: %v", err)
+	}
+	if got := len(publisher.Published()); got != 0 {
+		t.Errorf("published %d dead events, want 0", got)
+	}
+}
+
+func TestRelayDeadLettersUnreadablePayloads(t *testing.T) {
+	db := openTestDB(t)
+	if err := db.Create(&models.OutboxEvent{Payload: "{not json", Status: StatusPending, NextAttemptAt: time.Now()}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	publisher := events.NewMemoryPublisher()
+	if err := NewRelay(db, publisher).RelayPending(); err != nil {
+		t.Fatalf("relaying: %v", err)
+	}
+	if got := statuses(t, db); got[StatusDead] != 1 {
+		t.Errorf("statuses = %v, want the event dead-lettered", got)
+	}
+	if got := len(publisher.Published()); got != 0 {
+		t.Errorf("published %d events, want 0", got)
+	}
+}
+
+// Relays on several replicas share the outbox; each event goes out once.
+func TestConcurrentRelaysPublishEachEventOnce(t *testing.T) {
+	db := openTestDB(t)
+	const n = 50
+	enqueue(t, db, n)
+
+	publishers := make([]*events.MemoryPublisher, 4)
+	var wg sync.WaitGroup
+	for i := range publishers {
+		publishers[i] = events.NewMemoryPublisher()
+		wg.Add(1)
+		go func(publisher *events.MemoryPublisher) {
+			defer wg.Done()
+			if err := NewRelay(db, publisher).RelayPending(); err != nil {
+				t.Errorf("relaying: %v", err)
+			}
+		}(publishers[i])
+	}
+	wg.Wait()
+
+	seen := map[string]int{}
+	for _, publisher := range publishers {
+		for _, envelope := range publisher.Published() {
+			seen[envelope.EventID]++
+		}
+	}
+	if len(seen) != n {
+		t.Errorf("published %d distinct events, want %d", len(seen), n)
+	}
+	for id, count := range seen {
+		if count > 1 {
+			t.Errorf("event %s published %d times", id, count)
+		}
+	}
+}
+
+func TestPurgeSent(t *testing.T) {
+	db := openTestDB(t)
+	enqueue(t, db, 2)
+	if err := NewRelay(db, events.NewMemoryPublisher()).RelayPending(); err != nil {
+		t.Fatalf("relaying: %v", err)
+	}
+	enqueue(t, db, 1)
+
+	if n, err := PurgeSent(db, time.Now().Add(-time.Hour)); err != nil || n != 0 {
+		t.Fatalf("purging recent events: %d deleted, %v; want none", n, err)
+	}
+	if n, err := PurgeSent(db, time.Now().Add(time.Second)); err != nil || n != 2 {
+		t.Fatalf("purging: %d deleted, %v; want 2", n, err)
+	}
+	if got := statuses(t, db); got[StatusPending] != 1 || len(got) != 1 {
+		t.Errorf("statuses = %v, want only the pending event left", got)
+	}
+}
@@ -0,0 +1,115 @@
+package password
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+)
+
+//go:embed common.txt
+var commonList string
+
+var common = parseList(strings.NewReader(commonList))
+
+// MaxBytes is the longest password bcrypt can hash; it refuses anything
+// longer rather than truncating it.
+const MaxBytes = 72
+
+// Policy is the set of rules a new password must satisfy.
+type Policy struct {
+	MinLength int
+	// How many of lower case, upper case, digits and symbols must appear
+	RequiredClasses int
+	// How many previous passwords may not be reused, counting the current
+	// one. Zero allows any password to be reused
+	HistorySize int
+	// Extra lower-cased passwords to reject on top of the built-in list
+	Blocklist map[string]bool
+}
+
+// PolicyError lists every rule a password broke.
+type PolicyError struct {
+	Problems []string
+}
+
+func (e *PolicyError) Error() string {
+	return "password " + strings.Join(e.Problems, "; ")
+}
+
+// Check validates password against every rule except history, which needs
+// the stored hashes.
+func (p Policy) Check(password string) error {
+	var problems []string
+
+	if len([]rune(password)) < p.MinLength {
+		problems = append(problems, fmt.Sprintf("must be at least %d characters", p.MinLength))
+	}
+	if len(password) > MaxBytes {
+		problems = append(problems, fmt.Sprintf("must be at most %d bytes", MaxBytes))
+	}
+	if classes := characterClasses(password); classes < p.RequiredClasses {
+		problems = append(problems, fmt.Sprintf(
+			"must contain at least %d of: lower case letters, upper case letters, digits, symbols", p.RequiredClasses))
+	}
+	lower := strings.ToLower(password)
+	if common[lower] || p.Blocklist[lower] {
+		problems = append(problems, "is too common")
+	}
+
+	if len(problems) > 0 {
+		return &PolicyError{Problems: problems}
+	}
+	return nil
+}
+
+func characterClasses(password string) int {
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+
+	count := 0
+	for _, present := range []bool{lower, upper, digit, symbol} {
+		if present {
+			count++
+		}
+	}
+	return count
+}
+
+// LoadBlocklist reads a file of passwords to reject, one per line. Blank
+// lines and lines starting with # are ignored.
+func LoadBlocklist(path string) (map[string]bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return parseList(file), nil
+}
+
+func parseList(r io.Reader) map[string]bool {
+	list := map[string]bool{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		list[strings.ToLower(line)] = true
+	}
+	return list
+}
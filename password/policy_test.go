@@ -0,0 +1,105 @@
+package password
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	policy := Policy{MinLength: 8, RequiredClasses: 3, Blocklist: map[string]bool{"bankapp-2025!": true}}
+
+	cases := []struct {
+		name     string
+		password string
+		problems []string
+	}{
+		{"good", "Tr1cky-horse", nil},
+		{"three classes", "trickyhorse42!", nil},
+		{"exactly min length", "Abcdef1!", nil},
+		{"multibyte counts characters", "Ünïcödé1", nil},
+
+		{"too short", "Ab1!xyz", []string{"at least 8 characters"}},
+		{"multibyte too short", "Üñí1!ab", []string{"at least 8 characters"}},
+		{"two classes", "lowercase123", []string{"at least 3 of"}},
+		{"one class", "onlylowercase", []string{"at least 3 of"}},
+		{"common", "Password1", []string{"too common"}},
+		{"common ignores case", "QWERTY123", []string{"too common", "at least 3 of"}},
+		{"blocklisted", "BankApp-2025!", []string{"too common"}},
+		{"everything wrong", "letmein", []string{"at least 8 characters", "at least 3 of", "too common"}},
+		{"72 bytes", strings.Repeat("Ab1!", 18), nil},
+		{"73 bytes", strings.Repeat("Ab1!", 18) + "x", []string{"at most 72 bytes"}},
+		// 36 two byte characters fit; bcrypt limits bytes, not characters
+		{"multibyte over 72 bytes", strings.Repeat("Ü", 36) + "a1!", []string{"at most 72 bytes"}},
+	}
+	for _, tc := range cases {
+		err := policy.Check(tc.password)
+		if tc.problems == nil {
+			if err != nil {
+				t.Errorf("%s: %v", tc.name, err)
+			}
+			continue
+		}
+
+		var policyErr *PolicyError
+		if !errors.As(err, &policyErr) {
+			t.Errorf("%s: got %v, want a PolicyError", tc.name, err)
+			continue
+		}
+		if len(policyErr.Problems) != len(tc.problems) {
+			t.Errorf("%s: problems %q, want %d", tc.name, policyErr.Problems, len(tc.problems))
+			continue
+		}
+		for _, want := range tc.problems {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("%s: %q does not mention %q", tc.name, err, want)
+			}
+		}
+	}
+}
+
+func TestCheckRequiredClasses(t *testing.T) {
+	cases := []struct {
+		password string
+		classes  int
+	}{
+		{"abcdefgh", 1},
+		{"ABCDEFGH", 1},
+		{"12345678", 1},
+		{"!@#$%^&*", 1},
+		{"abcdEFGH", 2},
+		{"abcdEF12", 3},
+		{"abcD12 !", 4},
+		{"ümlaut ÄÖ", 3},
+	}
+	for _, tc := range cases {
+		if got := characterClasses(tc.password); got != tc.classes {
+			t.Errorf("characterClasses(%q) = %d, want %d", tc.password, got, tc.classes)
+		}
+	}
+
+	lenient := Policy{MinLength: 1, RequiredClasses: 0}
+	if err := lenient.Check("zz"); err != nil {
+		t.Errorf("no required classes: %v", err)
+	}
+}
+
+func TestLoadBlocklist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+	if err := os.WriteFile(path, []byte("# comment\n\n  Hunter2-Extra \nletmein-now\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	list, err := LoadBlocklist(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || !list["hunter2-extra"] || !list["letmein-now"] {
+		t.Errorf("blocklist = %v", list)
+	}
+
+	if _, err := LoadBlocklist(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("missing file accepted")
+	}
+}
@@ -0,0 +1,322 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+// State describes where a Connection is in its lifecycle.
+type State string
+
+const (
+	StateConnecting State = "connecting"
+	StateConnected  State = "connected"
+	StateClosed     State = "closed"
+)
+
+var (
+	ErrNotConnected   = errors.New("rabbitmq: not connected")
+	ErrNacked         = errors.New("rabbitmq: broker rejected the message")
+	ErrConfirmTimeout = errors.New("rabbitmq: timed out waiting for publisher confirm")
+)
+
+// TopologyFunc declares the exchanges and queues the application needs. It
+// runs again after every reconnect.
+type TopologyFunc func(channel *amqp.Channel) error
+
+// Connection keeps an AMQP connection and a confirm-mode channel open,
+// redialling with exponential backoff whenever the broker drops them.
+type Connection struct {
+	url      string
+	topology TopologyFunc
+
+	MinBackoff     time.Duration
+	MaxBackoff     time.Duration
+	ConfirmTimeout time.Duration
+
+	mu        sync.RWMutex
+	conn      *amqp.Connection
+	channel   *amqp.Channel
+	confirms  chan amqp.Confirmation
+	state     State
+	lastError error
+
+	// Publishes are serialised so each one can wait for its own confirm
+	publishMu sync.Mutex
+
+	done chan struct{}
+}
+
+// Dial starts connecting to url in the background and returns immediately;
+// an unreachable broker is retried rather than reported as an error.
+func Dial(url string, topology TopologyFunc) *Connection {
+	c := &Connection{
+		url:            url,
+		topology:       topology,
+		MinBackoff:     500 * time.Millisecond,
+		MaxBackoff:     30 * time.Second,
+		ConfirmTimeout: 5 * time.Second,
+		state:          StateConnecting,
+		done:           make(chan struct{}),
+	}
+	go c.run()
+	return c
+}
+
+func (c *Connection) run() {
+	backoff := c.MinBackoff
+	for {
+		if c.isClosed() {
+			return
+		}
+
+		closed, err := c.connect()
+		if err != nil {
+			c.setState(StateConnecting, err)
+			log.Printf("Failed to connect to RabbitMQ, retrying in %s: %v\n", backoff, err)
+
+			select {
+			case <-c.done:
+				return
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+			if backoff > c.MaxBackoff {
+				backoff = c.MaxBackoff
+			}
+			continue
+		}
+
+		backoff = c.MinBackoff
+		c.setState(StateConnected, nil)
+		log.Println("RabbitMQ connected successfully!")
+
+		select {
+		case <-c.done:
+			return
+		case amqpErr := <-closed:
+			if c.isClosed() {
+				return
+			}
+			err := fmt.Errorf("connection closed: %v", amqpErr)
+			c.setState(StateConnecting, err)
+			log.Printf("RabbitMQ %v, reconnecting\n", err)
+			c.teardown()
+		}
+	}
+}
+
+// connect dials the broker, opens a confirm-mode channel and declares the
+// topology. The returned channel fires when either the connection or the
+// channel closes.
+func (c *Connection) connect() (<-chan *amqp.Error, error) {
+	conn, err := amqp.Dial(c.url)
+	if err != nil {
+		return nil, err
+	}
+
+	channel, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := channel.Confirm(false); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if c.topology != nil {
+		if err := c.topology(channel); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	closed := make(chan *amqp.Error, 2)
+	conn.NotifyClose(forward(closed))
+	channel.NotifyClose(forward(closed))
+
+	c.mu.Lock()
+	c.conn = conn
+	c.channel = channel
+	c.confirms = channel.NotifyPublish(make(chan amqp.Confirmation, 1))
+	c.mu.Unlock()
+
+	return closed, nil
+}
+
+// forward relays the first close notification from an amqp channel into
+// out without blocking the library if nobody is listening any more.
+func forward(out chan *amqp.Error) chan *amqp.Error {
+	in := make(chan *amqp.Error, 1)
+	go func() {
+		err, ok := <-in
+		if !ok {
+			err = amqp.ErrClosed
+		}
+		select {
+		case out <- err:
+		default:
+		}
+	}()
+	return in
+}
+
+func (c *Connection) teardown() {
+	c.mu.Lock()
+	conn := c.conn
+	c.conn, c.channel, c.confirms = nil, nil, nil
+	c.mu.Unlock()
+
+	if conn != nil {
+		conn.Close()
+	}
+}
+
+func (c *Connection) setState(state State, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.state == StateClosed {
+		return
+	}
+	c.state = state
+	c.lastError = err
+}
+
+// State reports the current connection state and, if it is not connected,
+// the error that caused it.
+func (c *Connection) State() (State, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.state, c.lastError
+}
+
+// Publish sends msg and waits until the broker confirms it. A nil error
+// means the broker has taken responsibility for the message.
+func (c *Connection) Publish(exchange, routingKey string, msg amqp.Publishing) error {
+	c.publishMu.Lock()
+	defer c.publishMu.Unlock()
+
+	c.mu.RLock()
+	channel, confirms := c.channel, c.confirms
+	c.mu.RUnlock()
+	if channel == nil {
+		return ErrNotConnected
+	}
+
+	if err := channel.Publish(exchange, routingKey, false, false, msg); err != nil {
+		return err
+	}
+
+	select {
+	case confirm, ok := <-confirms:
+		if !ok {
+			return ErrNotConnected
+		}
+		if !confirm.Ack {
+			return ErrNacked
+		}
+		return nil
+	case <-time.After(c.ConfirmTimeout):
+		// A late confirm would be mistaken for the next message's, so start
+		// over on a fresh channel
+		c.teardown()
+		return ErrConfirmTimeout
+	}
+}
+
+func (c *Connection) isClosed() bool {
+	select {
+	case <-c.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *Connection) Close() error {
+	c.mu.Lock()
+	if c.state == StateClosed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.state, c.lastError = StateClosed, nil
+	close(c.done)
+	c.mu.Unlock()
+
+	c.teardown()
+	return nil
+}
+
+// Consume delivers messages from queue to handle until ctx is cancelled,
+// resubscribing on a fresh channel whenever the connection is re-established.
+// handle is responsible for acking or rejecting every delivery.
+func (c *Connection) Consume(ctx context.Context, queue string, prefetch int, handle func(amqp.Delivery)) {
+	for {
+		deliveries, channel, err := c.subscribe(queue, prefetch)
+		if err != nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-c.done:
+				return
+			case <-time.After(c.MinBackoff):
+			}
+			continue
+		}
+
+		log.Printf("Consuming from %s\n", queue)
+	receive:
+		for {
+			select {
+			case <-ctx.Done():
+				channel.Close()
+				return
+			case delivery, ok := <-deliveries:
+				if !ok {
+					log.Printf("Consumer on %s stopped, resubscribing\n", queue)
+					break receive
+				}
+				handle(delivery)
+			}
+		}
+	}
+}
+
+func (c *Connection) subscribe(queue string, prefetch int) (<-chan amqp.Delivery, *amqp.Channel, error) {
+	c.mu.RLock()
+	conn := c.conn
+	c.mu.RUnlock()
+	if conn == nil {
+		return nil, nil, ErrNotConnected
+	}
+
+	channel, err := conn.Channel()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := channel.Qos(prefetch, 0, false); err != nil {
+		channel.Close()
+		return nil, nil, err
+	}
+
+	deliveries, err := channel.Consume(
+		queue, // queue
+		"",    // consumer
+		false, // auto-ack
+		false, // exclusive
+		false, // no-local
+		false, // no-wait
+		nil,   // args
+	)
+	if err != nil {
+		channel.Close()
+		return nil, nil, err
+	}
+	return deliveries, channel, nil
+}
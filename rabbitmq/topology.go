@@ -0,0 +1,182 @@
+package rabbitmq
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+const (
+	defaultExchange   = "bank.events"
+	defaultRetryDelay = 30 * time.Second
+	defaultQueues     = "bank.notifications=account.#,transfer.#,security.account.#,user.#;bank.analytics=account.#,transfer.#,security.#"
+)
+
+// Queue is a consumer queue bound to the events exchange by topic patterns.
+type Queue struct {
+	Name     string
+	Patterns []string
+}
+
+// RetryQueue is where messages rejected from q wait before going back to it.
+func (q Queue) RetryQueue() string { return q.Name + ".retry" }
+
+// DeadQueue is where messages from q are parked once they exhaust their retries.
+func (q Queue) DeadQueue() string { return q.Name + ".dead" }
+
+// Topology describes every exchange and queue the application uses.
+//
+// Events are published to Exchange (a topic exchange) with their event type
+// as the routing key. A message rejected by a consumer is dead-lettered to
+// RetryExchange, waits RetryDelay in the queue's retry queue and then goes
+// back to the queue it came from. Consumers that give up on a message
+// publish it to DeadLetterExchange, which parks it in the queue's dead queue.
+type Topology struct {
+	Exchange           string
+	RetryExchange      string
+	DeadLetterExchange string
+	RetryDelay         time.Duration
+	Queues             []Queue
+}
+
+// LoadTopology reads the topology from the environment:
+//
+//	RABBITMQ_EXCHANGE     topic exchange name (default "bank.events")
+//	RABBITMQ_RETRY_DELAY  delay before a rejected message is redelivered (default 30s)
+//	RABBITMQ_QUEUES       "queue=pattern,pattern;queue=pattern" consumer queues
+func LoadTopology() (Topology, error) {
+	exchange := os.Getenv("RABBITMQ_EXCHANGE")
+	if exchange == "" {
+		exchange = defaultExchange
+	}
+
+	retryDelay := defaultRetryDelay
+	if value := os.Getenv("RABBITMQ_RETRY_DELAY"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed <= 0 {
+			return Topology{}, fmt.Errorf("invalid RABBITMQ_RETRY_DELAY %q", value)
+		}
+		retryDelay = parsed
+	}
+
+	spec := os.Getenv("RABBITMQ_QUEUES")
+	if spec == "" {
+		spec = defaultQueues
+	}
+	queues, err := parseQueues(spec)
+	if err != nil {
+		return Topology{}, err
+	}
+
+	return Topology{
+		Exchange:           exchange,
+		RetryExchange:      exchange + ".retry",
+		DeadLetterExchange: exchange + ".dlx",
+		RetryDelay:         retryDelay,
+		Queues:             queues,
+	}, nil
+}
+
+func parseQueues(spec string) ([]Queue, error) {
+	var queues []Queue
+	for _, entry := range strings.Split(spec, ";") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		name, patterns, ok := strings.Cut(entry, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" || strings.TrimSpace(patterns) == "" {
+			return nil, fmt.Errorf("invalid RABBITMQ_QUEUES entry %q", entry)
+		}
+
+		queue := Queue{Name: name}
+		for _, pattern := range strings.Split(patterns, ",") {
+			if pattern = strings.TrimSpace(pattern); pattern != "" {
+				queue.Patterns = append(queue.Patterns, pattern)
+			}
+		}
+		queues = append(queues, queue)
+	}
+	return queues, nil
+}
+
+// Queue looks up a consumer queue by name.
+func (t Topology) Queue(name string) (Queue, bool) {
+	for _, queue := range t.Queues {
+		if queue.Name == name {
+			return queue, true
+		}
+	}
+	return Queue{}, false
+}
+
+// Declare creates the exchanges, queues and bindings. Every declaration is
+// idempotent, so it is safe to run after each reconnect.
+func (t Topology) Declare(channel *amqp.Channel) error {
+	exchanges := []struct{ name, kind string }{
+		{t.Exchange, amqp.ExchangeTopic},
+		{t.RetryExchange, amqp.ExchangeDirect},
+		{t.DeadLetterExchange, amqp.ExchangeDirect},
+	}
+	for _, exchange := range exchanges {
+		if err := channel.ExchangeDeclare(
+			exchange.name, // name
+			exchange.kind, // type
+			true,          // durable
+			false,         // auto-deleted
+			false,         // internal
+			false,         // no-wait
+			nil,           // arguments
+		); err != nil {
+			return fmt.Errorf("declare exchange %s: %w", exchange.name, err)
+		}
+	}
+
+	for _, queue := range t.Queues {
+		if err := t.declareQueue(channel, queue); err != nil {
+			return err
+		}
+	}
+	log.Printf("RabbitMQ topology declared on exchange %s\n", t.Exchange)
+	return nil
+}
+
+func (t Topology) declareQueue(channel *amqp.Channel, queue Queue) error {
+	// Rejected messages go to the retry queue under the queue's own name
+	if _, err := channel.QueueDeclare(queue.Name, true, false, false, false, amqp.Table{
+		"x-dead-letter-exchange":    t.RetryExchange,
+		"x-dead-letter-routing-key": queue.Name,
+	}); err != nil {
+		return fmt.Errorf("declare queue %s: %w", queue.Name, err)
+	}
+	for _, pattern := range queue.Patterns {
+		if err := channel.QueueBind(queue.Name, pattern, t.Exchange, false, nil); err != nil {
+			return fmt.Errorf("bind queue %s to %s: %w", queue.Name, pattern, err)
+		}
+	}
+
+	// Once their TTL expires, retried messages return to the queue through
+	// the default exchange
+	if _, err := channel.QueueDeclare(queue.RetryQueue(), true, false, false, false, amqp.Table{
+		"x-message-ttl":             int32(t.RetryDelay / time.Millisecond),
+		"x-dead-letter-exchange":    "",
+		"x-dead-letter-routing-key": queue.Name,
+	}); err != nil {
+		return fmt.Errorf("declare queue %s: %w", queue.RetryQueue(), err)
+	}
+	if err := channel.QueueBind(queue.RetryQueue(), queue.Name, t.RetryExchange, false, nil); err != nil {
+		return fmt.Errorf("bind queue %s: %w", queue.RetryQueue(), err)
+	}
+
+	if _, err := channel.QueueDeclare(queue.DeadQueue(), true, false, false, false, nil); err != nil {
+		return fmt.Errorf("declare queue %s: %w", queue.DeadQueue(), err)
+	}
+	if err := channel.QueueBind(queue.DeadQueue(), queue.Name, t.DeadLetterExchange, false, nil); err != nil {
+		return fmt.Errorf("bind queue %s: %w", queue.DeadQueue(), err)
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package totp
+
+import (
+	"testing"
+	"time"
+)
+
+// RFC 6238 Appendix B test vectors for SHA1.
+var rfcVectors = []struct {
+	unix int64
+	code string
+}{
+	{59, "94287082"},
+	{1111111109, "07081804"},
+	{1111111111, "14050471"},
+	{1234567890, "89005924"},
+	{2000000000, "69279037"},
+	{20000000000, "65353130"},
+}
+
+var rfcKey = []byte("12345678901234567890")
+
+func TestRFC6238Vectors(t *testing.T) {
+	secret := encoding.EncodeToString(rfcKey)
+	for _, v := range rfcVectors {
+		step := Step(time.Unix(v.unix, 0))
+		if got := code(rfcKey, step, 8); got != v.code {
+			t.Errorf("8 digit code at %d = %s, want %s", v.unix, got, v.code)
+		}
+
+		// Six digit codes are the low digits of the eight digit ones
+		got, err := Code(secret, step)
+		if err != nil {
+			t.Fatalf("Code: %v", err)
+		}
+		if want := v.code[len(v.code)-Digits:]; got != want {
+			t.Errorf("code at %d = %s, want %s", v.unix, got, want)
+		}
+	}
+}
+
+func TestCodeRejectsBadSecret(t *testing.T) {
+	if _, err := Code("not base32!", 1); err == nil {
+		t.Error("invalid secret accepted")
+	}
+}
+
+func TestValidateSkewWindow(t *testing.T) {
+	secret := encoding.EncodeToString(rfcKey)
+	now := time.Unix(1111111111, 0)
+	current := Step(now)
+
+	for offset := int64(-3); offset <= 3; offset++ {
+		c, err := Code(secret, current+offset)
+		if err != nil {
+			t.Fatal(err)
+		}
+		step, ok := Validate(secret, c, now, 0)
+		want := offset >= -Skew && offset <= Skew
+		if ok != want {
+			t.Errorf("code %d steps away: accepted %v, want %v", offset, ok, want)
+		}
+		if ok && step != current+offset {
+			t.Errorf("code %d steps away matched step %d, want %d", offset, step, current+offset)
+		}
+	}
+}
+
+func TestValidateRejectsReplay(t *testing.T) {
+	secret := encoding.EncodeToString(rfcKey)
+	now := time.Unix(1111111111, 0)
+	current := Step(now)
+	c, _ := Code(secret, current)
+
+	step, ok := Validate(secret, c, now, current-1)
+	if !ok || step != current {
+		t.Fatalf("first use: step %d, %v; want %d, true", step, ok, current)
+	}
+	if _, ok := Validate(secret, c, now, step); ok {
+		t.Error("code accepted again after its step was used")
+	}
+	// Nor can an older code slip in once a later step has been used
+	previous, _ := Code(secret, current-1)
+	if _, ok := Validate(secret, previous, now, current); ok {
+		t.Error("code from an earlier step accepted after a later one was used")
+	}
+	// A later code is still fine
+	next, _ := Code(secret, current+1)
+	if step, ok := Validate(secret, next, now, current); !ok || step != current+1 {
+		t.Errorf("next step: %d, %v; want %d, true", step, ok, current+1)
+	}
+}
+
+func TestValidateInput(t *testing.T) {
+	secret := encoding.EncodeToString(rfcKey)
+	now := time.Unix(1111111111, 0)
+	c, _ := Code(secret, Step(now))
+
+	if _, ok := Validate(secret, " "+c+"\n", now, 0); !ok {
+		t.Error("code with surrounding whitespace rejected")
+	}
+	for _, bad := range []string{"", c[:Digits-1], c + "0", "abcdef"} {
+		if _, ok := Validate(secret, bad, now, 0); ok {
+			t.Errorf("code %q accepted", bad)
+		}
+	}
+	if _, ok := Validate("not base32!", c, now, 0); ok {
+		t.Error("code accepted for an invalid secret")
+	}
+}